@@ -0,0 +1,98 @@
+package todo
+
+import (
+	"sort"
+	"time"
+)
+
+// Counts and timings of the items of a list.
+type Stats struct {
+	Total          int
+	Open           int
+	Done           int
+	Overdue        int           // open items due before today
+	CompletionRate float64       // the share of the items that are done, from 0 to 1
+	MedianDone     time.Duration // the median time from creation to completion
+}
+
+// Returns the name of the archive file kept next to the list file.
+func ArchiveFilename(filename string) string {
+	return filename + ".archive"
+}
+
+// Moves the items completed before the time from the list to the archive and
+// returns them. Items with open descendants stay in the list. Archived items
+// keep their IDs, which are never reused by the list, and replace an older
+// copy of themselves in the archive. They leave a tombstone that is not sent
+// when syncing, so the other copies of the list keep them.
+func (l *TodoList) Archive(archive *TodoList, before time.Time) ([]TodoItem, error) {
+	// The items are picked before deleting any, so that archived subtasks
+	// keep their archived parents.
+	archived := []TodoItem{}
+	for _, item := range l.Items {
+		if item.Done && item.CompletedAt.Before(before) && len(l.openDescendants(item.ID)) == 0 {
+			archived = append(archived, item)
+		}
+	}
+
+	for _, item := range archived {
+		if err := l.Delete(item.ID); err != nil {
+			return nil, err
+		}
+
+		if item.UID != "" {
+			l.removeTombstone(item.UID)
+			// The tombstone dates from the last change to the item, so
+			// that later changes from other copies bring it back.
+			l.Deleted = append(l.Deleted, Tombstone{UID: item.UID, At: item.lastModified(), Archived: true})
+		}
+
+		if i, err := archive.indexOf(item.ID); err == nil {
+			archive.Items[i] = item
+		} else {
+			archive.Items = append(archive.Items, item)
+		}
+
+		if item.ID >= archive.NextID {
+			archive.NextID = item.ID + 1
+		}
+	}
+
+	return archived, nil
+}
+
+// Returns the counts and timings of the items.
+func (l *TodoList) Stats(now time.Time) Stats {
+	stats := Stats{Total: len(l.Items)}
+	durations := []time.Duration{}
+
+	for _, item := range l.Items {
+		if !item.Done {
+			stats.Open++
+			if !item.Due.IsZero() && compareDays(item.Due, now) < 0 {
+				stats.Overdue++
+			}
+			continue
+		}
+
+		stats.Done++
+		if !item.CreatedAt.IsZero() && !item.CompletedAt.IsZero() {
+			durations = append(durations, item.CompletedAt.Sub(item.CreatedAt))
+		}
+	}
+
+	if stats.Total > 0 {
+		stats.CompletionRate = float64(stats.Done) / float64(stats.Total)
+	}
+
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+
+	if n := len(durations); n > 0 {
+		stats.MedianDone = durations[n/2]
+		if n%2 == 0 {
+			stats.MedianDone = (durations[n/2-1] + durations[n/2]) / 2
+		}
+	}
+
+	return stats
+}
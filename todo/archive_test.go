@@ -0,0 +1,107 @@
+package todo_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"mnishiguchi.com/todo"
+)
+
+func TestArchive(t *testing.T) {
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	daysAgo := func(n int) time.Time { return now.AddDate(0, 0, -n) }
+
+	list := todo.TodoList{NextID: 7, Items: []todo.TodoItem{
+		{ID: 1, Task: "Ship release", Done: true, CompletedAt: daysAgo(40), UID: "a1"},
+		{ID: 2, Task: "Write changelog", Done: true, CompletedAt: daysAgo(40), ParentID: 1},
+		{ID: 3, Task: "Pay bills", Done: true, CompletedAt: daysAgo(1)},
+		{ID: 4, Task: "Plan the launch", Done: true, CompletedAt: daysAgo(40)},
+		{ID: 5, Task: "Book the room", ParentID: 4},
+		{ID: 6, Task: "Fix login bug", BlockedBy: []int{1}},
+	}}
+	archive := todo.TodoList{Items: []todo.TodoItem{
+		{ID: 1, Task: "Ship release", CompletedAt: daysAgo(50)},
+	}}
+
+	archived, err := list.Archive(&archive, daysAgo(30))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ids := idsOf(archived); !reflect.DeepEqual(ids, []int{1, 2}) {
+		t.Errorf("Expected %v archived, got %v instead", []int{1, 2}, ids)
+	}
+
+	if ids := idsOf(list.Items); !reflect.DeepEqual(ids, []int{3, 4, 5, 6}) {
+		t.Errorf("Expected %v left, got %v instead", []int{3, 4, 5, 6}, ids)
+	}
+
+	// The archived item replaces its older copy and the subtask keeps its
+	// parent.
+	expected := "[X] 1: Ship release\n  [X] 2: Write changelog\n"
+	if res := archive.String(); res != expected {
+		t.Errorf("Expected %q, got %q instead", expected, res)
+	}
+
+	if blockers := list.Items[3].BlockedBy; blockers != nil {
+		t.Errorf("Expected no blockers left, got %v instead", blockers)
+	}
+
+	// Archived items leave a tombstone that is not synced.
+	if deleted := list.Deleted; len(deleted) != 1 || deleted[0].UID != "a1" || !deleted[0].Archived {
+		t.Errorf("Expected an archived tombstone for a1, got %+v instead", deleted)
+	}
+
+	if state := list.SyncState(); len(state.Deleted) != 0 {
+		t.Errorf("Expected no tombstones to sync, got %+v instead", state.Deleted)
+	}
+
+	// New items do not reuse the archived IDs.
+	list.Add("Tag the commit")
+	if id := list.Items[len(list.Items)-1].ID; id != 7 {
+		t.Errorf("Expected ID %d, got %d instead", 7, id)
+	}
+}
+
+func TestStats(t *testing.T) {
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	created := now.AddDate(0, 0, -10)
+
+	list := todo.TodoList{Items: []todo.TodoItem{
+		{ID: 1, Task: "a", Done: true, CreatedAt: created, CompletedAt: created.Add(2 * time.Hour)},
+		{ID: 2, Task: "b", Done: true, CreatedAt: created, CompletedAt: created.Add(6 * time.Hour)},
+		{ID: 3, Task: "c", Done: true, CreatedAt: created, CompletedAt: created.Add(48 * time.Hour)},
+		{ID: 4, Task: "d", Done: true, CreatedAt: created, CompletedAt: created.Add(72 * time.Hour)},
+		{ID: 5, Task: "e", Due: now.AddDate(0, 0, -1)},
+		{ID: 6, Task: "f", Due: now},
+		{ID: 7, Task: "g"},
+		{ID: 8, Task: "h", Done: true},
+	}}
+
+	expected := todo.Stats{
+		Total:          8,
+		Open:           3,
+		Done:           5,
+		Overdue:        1,
+		CompletionRate: 0.625,
+		MedianDone:     27 * time.Hour,
+	}
+
+	if res := list.Stats(now); res != expected {
+		t.Errorf("Expected %+v, got %+v instead", expected, res)
+	}
+
+	if res := (&todo.TodoList{}).Stats(now); res != (todo.Stats{}) {
+		t.Errorf("Expected empty stats, got %+v instead", res)
+	}
+}
+
+func idsOf(items []todo.TodoItem) []int {
+	ids := []int{}
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+
+	return ids
+}
@@ -0,0 +1,1021 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"mnishiguchi.com/todo"
+)
+
+// A subcommand of the CLI, such as "todo add".
+type command struct {
+	Name  string
+	Args  string // the arguments after the flags, shown in the usage
+	Short string // a one-line description shown in the list of commands
+
+	// Defines the flags of the command on fs and returns the function that
+	// runs the command with the arguments left after parsing them.
+	Setup func(fs *flag.FlagSet) func(args []string) error
+}
+
+// The subcommands in the order they are listed in the usage.
+var commands = []command{
+	{
+		Name:  "add",
+		Args:  "[TASK...]",
+		Short: "Add a task, read from STDIN when no task is given",
+		Setup: func(fs *flag.FlagSet) func(args []string) error {
+			parentID := fs.Int("parent", 0, "ID of the parent task")
+
+			return func(args []string) error {
+				return addAction(os.Stdin, args, *parentID)
+			}
+		},
+	},
+	{
+		Name:  "list",
+		Short: "List the tasks",
+		Setup: func(fs *flag.FlagSet) func(args []string) error {
+			filterExpr := fs.String("filter", "", "Filter expression, such as 'not done and tag:backend'")
+			sortSpec := fs.String("sort", "", "Comma-separated sort keys, such as 'priority,due'")
+			ready := fs.Bool("ready", false, "Only list open tasks whose blockers are done")
+
+			return func(args []string) error {
+				if err := checkArgs(args, 0, 0); err != nil {
+					return err
+				}
+				return listTasks(os.Stdout, *filterExpr, *sortSpec, *ready)
+			}
+		},
+	},
+	{
+		Name:  "done",
+		Args:  "ID...",
+		Short: "Mark tasks as completed",
+		Setup: func(fs *flag.FlagSet) func(args []string) error {
+			force := fs.Bool("force", false, "Also complete the open subtasks")
+
+			return func(args []string) error {
+				if *force {
+					return eachIDAction("done -force", args, (*todo.TodoList).ForceComplete)
+				}
+				return eachIDAction("done", args, (*todo.TodoList).Complete)
+			}
+		},
+	},
+	{
+		Name:  "undone",
+		Args:  "ID...",
+		Short: "Mark tasks as not completed",
+		Setup: func(fs *flag.FlagSet) func(args []string) error {
+			return func(args []string) error {
+				return eachIDAction("undone", args, (*todo.TodoList).Uncomplete)
+			}
+		},
+	},
+	{
+		Name:  "start",
+		Args:  "ID",
+		Short: "Start working on a task, stopping the task in progress",
+		Setup: func(fs *flag.FlagSet) func(args []string) error {
+			return func(args []string) error {
+				return startAction(os.Stdout, args)
+			}
+		},
+	},
+	{
+		Name:  "stop",
+		Args:  "ID",
+		Short: "Stop working on a task",
+		Setup: func(fs *flag.FlagSet) func(args []string) error {
+			return func(args []string) error {
+				return stopAction(os.Stdout, args)
+			}
+		},
+	},
+	{
+		Name:  "report",
+		Short: "Show the time spent per task and per tag",
+		Setup: func(fs *flag.FlagSet) func(args []string) error {
+			since := fs.String("since", "7d", "Start of the report: a date, today, or a number of days ago such as 7d or 2w")
+			format := fs.String("format", todo.FormatText, "Output format: text or csv")
+
+			return func(args []string) error {
+				if err := checkArgs(args, 0, 0); err != nil {
+					return err
+				}
+				return reportAction(os.Stdout, *since, *format)
+			}
+		},
+	},
+	{
+		Name:  "edit",
+		Args:  "ID [TASK...]",
+		Short: "Replace the text of a task, read from STDIN when no task is given",
+		Setup: func(fs *flag.FlagSet) func(args []string) error {
+			return func(args []string) error {
+				return editAction(os.Stdin, args)
+			}
+		},
+	},
+	{
+		Name:  "rm",
+		Args:  "ID...",
+		Short: "Delete tasks",
+		Setup: func(fs *flag.FlagSet) func(args []string) error {
+			return func(args []string) error {
+				return eachIDAction("rm", args, (*todo.TodoList).Delete)
+			}
+		},
+	},
+	{
+		Name:  "block",
+		Args:  "ID BLOCKER_ID...",
+		Short: "Make a task wait until other tasks are done",
+		Setup: func(fs *flag.FlagSet) func(args []string) error {
+			return func(args []string) error {
+				return linkAction("block", args, (*todo.TodoList).Block)
+			}
+		},
+	},
+	{
+		Name:  "unblock",
+		Args:  "ID BLOCKER_ID...",
+		Short: "Stop a task from waiting on other tasks",
+		Setup: func(fs *flag.FlagSet) func(args []string) error {
+			return func(args []string) error {
+				return linkAction("unblock", args, (*todo.TodoList).Unblock)
+			}
+		},
+	},
+	{
+		Name:  "search",
+		Args:  "TERM...",
+		Short: "List the tasks matching every term, allowing typos, best matches first",
+		Setup: func(fs *flag.FlagSet) func(args []string) error {
+			archived := fs.Bool("archive", false, "Search the archived tasks instead")
+
+			return func(args []string) error {
+				return searchAction(os.Stdout, args, *archived)
+			}
+		},
+	},
+	{
+		Name:  "show",
+		Args:  "ID",
+		Short: "Show the details of a task",
+		Setup: func(fs *flag.FlagSet) func(args []string) error {
+			return func(args []string) error {
+				return showAction(os.Stdout, args)
+			}
+		},
+	},
+	{
+		Name:  "lists",
+		Short: "List the lists of the workspace with their numbers of open and done tasks",
+		Setup: func(fs *flag.FlagSet) func(args []string) error {
+			return func(args []string) error {
+				if err := checkArgs(args, 0, 0); err != nil {
+					return err
+				}
+				return listsAction(os.Stdout)
+			}
+		},
+	},
+	{
+		Name:  "move",
+		Args:  "ID LIST",
+		Short: "Move a task and its subtasks to another list",
+		Setup: func(fs *flag.FlagSet) func(args []string) error {
+			return func(args []string) error {
+				return moveAction(os.Stdout, args)
+			}
+		},
+	},
+	{
+		Name:  "export",
+		Short: "Write the tasks to STDOUT as todo.txt, CSV, a Markdown checklist or JSON",
+		Setup: func(fs *flag.FlagSet) func(args []string) error {
+			format := fs.String("format", todo.FormatTodoTxt, "Output format: todotxt, csv, markdown or json")
+
+			return func(args []string) error {
+				if err := checkArgs(args, 0, 0); err != nil {
+					return err
+				}
+				return exportAction(os.Stdout, *format)
+			}
+		},
+	},
+	{
+		Name:  "import",
+		Args:  "FILE",
+		Short: "Add the tasks from a file, or from STDIN when FILE is -, skipping duplicates",
+		Setup: func(fs *flag.FlagSet) func(args []string) error {
+			format := fs.String("format", "", "Input format: todotxt, csv, markdown or json (default: detected)")
+
+			return func(args []string) error {
+				if err := checkArgs(args, 1, 1); err != nil {
+					return err
+				}
+				return importAction(os.Stdin, os.Stdout, args[0], *format)
+			}
+		},
+	},
+	{
+		Name:  "archive",
+		Short: "Move old completed tasks to the archive file of the list",
+		Setup: func(fs *flag.FlagSet) func(args []string) error {
+			olderThan := fs.String("older-than", "30d", "Archive the tasks completed before this date, or this many days ago such as 30d")
+
+			return func(args []string) error {
+				if err := checkArgs(args, 0, 0); err != nil {
+					return err
+				}
+				return archiveAction(os.Stdout, *olderThan)
+			}
+		},
+	},
+	{
+		Name:  "stats",
+		Short: "Show the numbers of tasks, the completion rate and the median time to complete a task",
+		Setup: func(fs *flag.FlagSet) func(args []string) error {
+			return func(args []string) error {
+				if err := checkArgs(args, 0, 0); err != nil {
+					return err
+				}
+				return statsAction(os.Stdout)
+			}
+		},
+	},
+	{
+		Name:  "sync",
+		Short: "Push and pull changes between the list and a todo_server",
+		Setup: func(fs *flag.FlagSet) func(args []string) error {
+			remote := fs.String("remote", os.Getenv("TODO_REMOTE"), "URL of the server, such as http://host:8080 (env TODO_REMOTE)")
+
+			return func(args []string) error {
+				if err := checkArgs(args, 0, 0); err != nil {
+					return err
+				}
+				return syncAction(os.Stdout, *remote)
+			}
+		},
+	},
+	{
+		Name:  "undo",
+		Short: "Undo the last change",
+		Setup: func(fs *flag.FlagSet) func(args []string) error {
+			return func(args []string) error {
+				return undoAction(os.Stdout, args, (*todo.Journal).Undo, "Undid")
+			}
+		},
+	},
+	{
+		Name:  "redo",
+		Short: "Redo the last undone change",
+		Setup: func(fs *flag.FlagSet) func(args []string) error {
+			return func(args []string) error {
+				return undoAction(os.Stdout, args, (*todo.Journal).Redo, "Redid")
+			}
+		},
+	},
+	{
+		Name:  "history",
+		Short: "Show the changes with their timestamps",
+		Setup: func(fs *flag.FlagSet) func(args []string) error {
+			return func(args []string) error {
+				if err := checkArgs(args, 0, 0); err != nil {
+					return err
+				}
+				return showHistory(os.Stdout)
+			}
+		},
+	},
+}
+
+// Returns the command with the given name.
+func findCommand(name string) (command, error) {
+	for _, cmd := range commands {
+		if cmd.Name == name {
+			return cmd, nil
+		}
+	}
+
+	return command{}, fmt.Errorf("%w: %q", ErrUnknownCmd, name)
+}
+
+// Parses the flags of the command and runs it. "-h" prints the usage of the
+// command to STDOUT.
+func runCommand(cmd command, args []string) error {
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	run := cmd.Setup(fs)
+
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			printCommandUsage(os.Stdout, cmd)
+			return nil
+		}
+
+		return fmt.Errorf("%w: %v", ErrInvalidFlag, err)
+	}
+
+	return run(fs.Args())
+}
+
+// Prints the usage of a command with its flags.
+func printCommandUsage(w io.Writer, cmd command) {
+	fmt.Fprintf(w, "Usage: todo [global flags] %s [flags] %s\n\n%s\n", cmd.Name, cmd.Args, cmd.Short)
+
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	cmd.Setup(fs)
+
+	hasFlags := false
+	fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+
+	if hasFlags {
+		fmt.Fprintln(w, "\nFlags:")
+		fs.SetOutput(w)
+		fs.PrintDefaults()
+	}
+}
+
+// Checks that the number of arguments is between min and max; a negative max
+// means no limit.
+func checkArgs(args []string, min, max int) error {
+	if len(args) < min {
+		return ErrMissingArgs
+	}
+
+	if max >= 0 && len(args) > max {
+		return fmt.Errorf("%w: %q", ErrTooManyArgs, strings.Join(args[max:], " "))
+	}
+
+	return nil
+}
+
+// Converts the arguments into item IDs.
+func parseIDs(args []string) ([]int, error) {
+	ids := []int{}
+
+	for _, arg := range args {
+		id, err := strconv.Atoi(arg)
+		if err != nil || id < 1 {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidID, arg)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// Adds the task given as arguments or read from r, as a child of the parent
+// unless parentID is zero.
+func addAction(r io.Reader, args []string, parentID int) error {
+	task, err := getTask(r, args...)
+	if err != nil {
+		return err
+	}
+
+	return update("add: "+task, func(list *todo.TodoList) error {
+		if err := list.Add(task); err != nil {
+			return err
+		}
+		if parentID == 0 {
+			return nil
+		}
+		return list.SetParent(list.Items[len(list.Items)-1].ID, parentID)
+	})
+}
+
+// Applies the change to every item whose ID is given, as a single operation
+// that can be undone at once.
+func eachIDAction(name string, args []string, change func(*todo.TodoList, int) error) error {
+	if err := checkArgs(args, 1, -1); err != nil {
+		return err
+	}
+
+	ids, err := parseIDs(args)
+	if err != nil {
+		return err
+	}
+
+	return update(name+": "+strings.Join(args, " "), func(list *todo.TodoList) error {
+		for _, id := range ids {
+			if err := change(list, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Links the item whose ID is the first argument to each of the other items.
+func linkAction(name string, args []string, link func(*todo.TodoList, int, int) error) error {
+	if err := checkArgs(args, 2, -1); err != nil {
+		return err
+	}
+
+	ids, err := parseIDs(args)
+	if err != nil {
+		return err
+	}
+
+	return update(name+": "+strings.Join(args, " "), func(list *todo.TodoList) error {
+		for _, otherID := range ids[1:] {
+			if err := link(list, ids[0], otherID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Replaces the task of the item whose ID is the first argument with the rest
+// of the arguments, or with a line read from r.
+func editAction(r io.Reader, args []string) error {
+	if err := checkArgs(args, 1, -1); err != nil {
+		return err
+	}
+
+	ids, err := parseIDs(args[:1])
+	if err != nil {
+		return err
+	}
+
+	task, err := getTask(r, args[1:]...)
+	if err != nil {
+		return err
+	}
+
+	return update(fmt.Sprintf("edit: %d %s", ids[0], task), func(list *todo.TodoList) error {
+		return list.Edit(ids[0], task)
+	})
+}
+
+// Starts a work session on the item whose ID is given and prints what was
+// started and stopped.
+func startAction(w io.Writer, args []string) error {
+	if err := checkArgs(args, 1, 1); err != nil {
+		return err
+	}
+
+	ids, err := parseIDs(args)
+	if err != nil {
+		return err
+	}
+
+	var started, stopped todo.TodoItem
+	err = update("start: "+args[0], func(list *todo.TodoList) error {
+		stoppedID, err := list.Start(ids[0], time.Now())
+		if err != nil {
+			return err
+		}
+
+		if stoppedID != 0 {
+			stopped, _ = list.Find(stoppedID)
+		}
+		started, err = list.Find(ids[0])
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if stopped.ID != 0 {
+		if err := printStopped(w, stopped); err != nil {
+			return err
+		}
+	}
+
+	_, err = fmt.Fprintf(w, "Started %d: %s\n", started.ID, started.Task)
+	return err
+}
+
+// Stops the work session of the item whose ID is given and prints how long it
+// lasted.
+func stopAction(w io.Writer, args []string) error {
+	if err := checkArgs(args, 1, 1); err != nil {
+		return err
+	}
+
+	ids, err := parseIDs(args)
+	if err != nil {
+		return err
+	}
+
+	var stopped todo.TodoItem
+	err = update("stop: "+args[0], func(list *todo.TodoList) error {
+		if err := list.Stop(ids[0], time.Now()); err != nil {
+			return err
+		}
+
+		stopped, err = list.Find(ids[0])
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return printStopped(w, stopped)
+}
+
+// Prints the item with the duration of its last session.
+func printStopped(w io.Writer, item todo.TodoItem) error {
+	last := item.Sessions[len(item.Sessions)-1]
+	_, err := fmt.Fprintf(w, "Stopped %d: %s after %s\n", item.ID, item.Task, todo.FormatDuration(last.End.Sub(last.Start)))
+	return err
+}
+
+// Prints the time spent on the items since the given time in the format.
+func reportAction(w io.Writer, since, format string) error {
+	now := time.Now()
+
+	start, err := todo.ParseSince(since, now)
+	if err != nil {
+		return err
+	}
+
+	list := &todo.TodoList{}
+	if err := todoStore.Load(list); err != nil {
+		return err
+	}
+
+	return list.TimeReport(start, now).Write(w, format)
+}
+
+// Prints the items matching the terms, best matches first; see TodoList.Search.
+// The archive is searched instead of the list when archived is true.
+func searchAction(w io.Writer, terms []string, archived bool) error {
+	if err := checkArgs(terms, 1, -1); err != nil {
+		return err
+	}
+
+	list := &todo.TodoList{}
+	if archived {
+		if err := list.Get(todo.ArchiveFilename(todoFileName)); err != nil {
+			return err
+		}
+	} else if err := todoStore.Load(list); err != nil {
+		return err
+	}
+
+	// Each result is printed on its own, so that the results stay in the
+	// order of their ranking instead of being grouped under their parents.
+	for _, result := range list.Search(strings.Join(terms, " "), time.Now()) {
+		if _, err := fmt.Fprint(w, &todo.TodoList{Items: []todo.TodoItem{result.Item}}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Prints every field of the item whose ID is given.
+func showAction(w io.Writer, args []string) error {
+	if err := checkArgs(args, 1, 1); err != nil {
+		return err
+	}
+
+	ids, err := parseIDs(args)
+	if err != nil {
+		return err
+	}
+
+	list := &todo.TodoList{}
+	if err := todoStore.Load(list); err != nil {
+		return err
+	}
+
+	item, err := list.Find(ids[0])
+	if err != nil {
+		return err
+	}
+
+	status := "open"
+	if item.Done {
+		status = "done"
+	}
+
+	// The completion days of the other occurrences of a recurring item
+	series, err := list.Series(item.ID)
+	if err != nil {
+		return err
+	}
+
+	completions := []string{}
+	for _, occurrence := range series {
+		if occurrence.Done && occurrence.ID != item.ID {
+			completions = append(completions, formatTime(occurrence.CompletedAt, todo.DateLayout))
+		}
+	}
+
+	// The time spent, with a running session lasting until now
+	spent, started := time.Duration(0), time.Time{}
+	for _, session := range item.Sessions {
+		end := session.End
+		if end.IsZero() {
+			end, started = time.Now(), session.Start
+		}
+		spent += end.Sub(session.Start)
+	}
+
+	timeSpent := ""
+	if len(item.Sessions) > 0 {
+		timeSpent = todo.FormatDuration(spent)
+	}
+
+	fields := [][2]string{
+		{"ID", strconv.Itoa(item.ID)},
+		{"Task", item.Task},
+		{"Status", status},
+		{"Priority", item.Priority},
+		{"Projects", strings.Join(item.Projects, ", ")},
+		{"Contexts", strings.Join(item.Contexts, ", ")},
+		{"Due", formatTime(item.Due, todo.DateLayout)},
+		{"Recurs", item.Recur},
+		{"Parent", formatIDs([]int{item.ParentID})},
+		{"Blocked by", formatIDs(item.BlockedBy)},
+		{"Done on", strings.Join(completions, ", ")},
+		{"Created", formatTime(item.CreatedAt, "2006-01-02 15:04:05")},
+		{"Completed", formatTime(item.CompletedAt, "2006-01-02 15:04:05")},
+		{"Time spent", timeSpent},
+		{"Started", formatTime(started, "2006-01-02 15:04:05")},
+	}
+
+	for _, field := range fields {
+		if field[1] == "" {
+			continue
+		}
+
+		if _, err := fmt.Fprintf(w, "%-11s %s\n", field[0]+":", field[1]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Formats the non-zero IDs as a comma-separated list.
+func formatIDs(ids []int) string {
+	formatted := []string{}
+	for _, id := range ids {
+		if id != 0 {
+			formatted = append(formatted, strconv.Itoa(id))
+		}
+	}
+
+	return strings.Join(formatted, ", ")
+}
+
+// Formats the time with the layout, or returns an empty string for zero.
+func formatTime(t time.Time, layout string) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.Format(layout)
+}
+
+// Prints every list of the workspace, marking the current one with "*".
+func listsAction(w io.Writer) error {
+	summaries, err := workspace.Summaries()
+	if err != nil {
+		return err
+	}
+
+	for _, summary := range summaries {
+		marker := " "
+		if summary.Name == todoListName {
+			marker = "*"
+		}
+
+		_, err := fmt.Fprintf(w, "%s %-20s %4d open %4d done\n", marker, summary.Name, summary.Open, summary.Done)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Moves the item whose ID is the first argument, with its subtasks, from the
+// current list to the list named by the second argument. A move spans two
+// lists, so it is not recorded in the journals and cannot be undone, and the
+// operations on the moved items are dropped from the journal of the current
+// list. The two lists are saved one after the other, not atomically: the
+// destination is saved first, so that a crash in between leaves the items in
+// both lists rather than in neither.
+func moveAction(w io.Writer, args []string) error {
+	if err := checkArgs(args, 2, 2); err != nil {
+		return err
+	}
+
+	ids, err := parseIDs(args[:1])
+	if err != nil {
+		return err
+	}
+
+	toStore, toFileName, err := workspace.Open(args[1])
+	if err != nil {
+		return err
+	}
+
+	if toFileName == todoFileName {
+		return fmt.Errorf("%w: %s", ErrSameList, args[1])
+	}
+
+	// Every process locks the two lists in the same order, so that two moves
+	// in opposite directions cannot wait on each other forever.
+	filenames := []string{todoFileName, toFileName}
+	sort.Strings(filenames)
+
+	for _, filename := range filenames {
+		lock, err := todo.Lock(filename)
+		if err != nil {
+			return err
+		}
+		defer lock.Unlock()
+	}
+
+	from, fromJournal, err := load(todoStore, todoFileName)
+	if err != nil {
+		return err
+	}
+
+	to := &todo.TodoList{}
+	if err := toStore.Load(to); err != nil {
+		return err
+	}
+
+	fromBefore, err := from.Clone()
+	if err != nil {
+		return err
+	}
+
+	toBefore, err := to.Clone()
+	if err != nil {
+		return err
+	}
+
+	moved, err := todo.MoveItem(from, to, ids[0])
+	if err != nil {
+		return err
+	}
+
+	gone := []int{}
+	for _, item := range fromBefore.Items {
+		if _, err := from.Find(item.ID); err != nil {
+			gone = append(gone, item.ID)
+		}
+	}
+	fromJournal.Forget(gone...)
+
+	now := time.Now()
+	from.Stamp(fromBefore, now)
+	to.Stamp(toBefore, now)
+
+	if err := toStore.Save(to); err != nil {
+		return err
+	}
+
+	if err := save(todoStore, todoFileName, from, fromJournal); err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "Moved %d to %s as %d\n", ids[0], args[1], moved[0].ID)
+	return err
+}
+
+// Writes the whole list in the format.
+func exportAction(w io.Writer, format string) error {
+	list := &todo.TodoList{}
+	if err := todoStore.Load(list); err != nil {
+		return err
+	}
+
+	return list.Export(w, format)
+}
+
+// Adds the items read from the file, or from r when the file name is "-", and
+// prints what was added. The format is detected unless it is given.
+func importAction(r io.Reader, w io.Writer, filename, format string) error {
+	var data []byte
+	var err error
+
+	if filename == "-" {
+		data, err = io.ReadAll(r)
+	} else {
+		data, err = os.ReadFile(filename)
+	}
+	if err != nil {
+		return err
+	}
+
+	if format == "" {
+		format = todo.DetectFormat(filename, data)
+	}
+
+	items, err := todo.ParseItems(bytes.NewReader(data), format)
+	if err != nil {
+		return err
+	}
+
+	result := todo.ImportResult{}
+	err = update("import: "+filename, func(list *todo.TodoList) error {
+		result = list.Import(items)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprint(w, &todo.TodoList{Items: result.Added}); err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "Imported %d tasks from %s, skipped %d duplicates\n", len(result.Added), format, result.Skipped)
+	return err
+}
+
+// Moves the items completed before the given date to the archive file, then
+// compacts the list file when its store keeps a history.
+func archiveAction(w io.Writer, olderThan string) error {
+	before, err := todo.ParseSince(olderThan, time.Now())
+	if err != nil {
+		return err
+	}
+
+	archiveFileName := todo.ArchiveFilename(todoFileName)
+	archived := []todo.TodoItem{}
+
+	// Archiving is not journaled, since undoing it would leave the items in
+	// the archive as well, and the operations on the archived items can no
+	// longer be undone.
+	err = transact(func(list *todo.TodoList, journal *todo.Journal) error {
+		archive := &todo.TodoList{}
+		if err := archive.Get(archiveFileName); err != nil {
+			return err
+		}
+
+		archived, err = list.Archive(archive, before)
+		if err != nil || len(archived) == 0 {
+			return err
+		}
+
+		ids := []int{}
+		for _, item := range archived {
+			ids = append(ids, item.ID)
+		}
+		journal.Forget(ids...)
+
+		// The archive is saved before the list, so that a crash in between
+		// cannot lose the items.
+		return archive.Save(archiveFileName)
+	})
+	if err != nil {
+		return err
+	}
+
+	if compacter, ok := todoStore.(todo.Compacter); ok && len(archived) > 0 {
+		if err := compact(compacter, todoStore, todoFileName); err != nil {
+			return err
+		}
+	}
+
+	_, err = fmt.Fprintf(w, "Archived %d tasks to %s\n", len(archived), archiveFileName)
+	return err
+}
+
+// Prints the statistics of the list and its archive together.
+func statsAction(w io.Writer) error {
+	list := &todo.TodoList{}
+	if err := todoStore.Load(list); err != nil {
+		return err
+	}
+
+	archive := &todo.TodoList{}
+	if err := archive.Get(todo.ArchiveFilename(todoFileName)); err != nil {
+		return err
+	}
+
+	all := &todo.TodoList{Items: append(list.Items, archive.Items...)}
+	stats := all.Stats(time.Now())
+
+	median := ""
+	if stats.MedianDone > 0 {
+		median = formatDays(stats.MedianDone)
+	}
+
+	fields := [][2]string{
+		{"Tasks", strconv.Itoa(stats.Total)},
+		{"Open", strconv.Itoa(stats.Open)},
+		{"Overdue", strconv.Itoa(stats.Overdue)},
+		{"Done", strconv.Itoa(stats.Done)},
+		{"Archived", strconv.Itoa(len(archive.Items))},
+		{"Completion", fmt.Sprintf("%.1f%%", stats.CompletionRate*100)},
+		{"Median time", median},
+	}
+
+	for _, field := range fields {
+		if field[1] == "" {
+			continue
+		}
+
+		if _, err := fmt.Fprintf(w, "%-12s %s\n", field[0]+":", field[1]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Formats a duration in days and hours, such as 2d03h, or in hours and
+// minutes when it is less than a day.
+func formatDays(d time.Duration) string {
+	if d < 24*time.Hour {
+		return todo.FormatDuration(d)
+	}
+
+	hours := int(d.Round(time.Hour) / time.Hour)
+	return fmt.Sprintf("%dd%02dh", hours/24, hours%24)
+}
+
+// Syncs the list with the server at the URL and prints what changed on each
+// side. Like any other change, a sync can be undone.
+func syncAction(w io.Writer, url string) error {
+	if url == "" {
+		return fmt.Errorf("%w: -remote", ErrMissingArgs)
+	}
+
+	remote := &todo.Remote{URL: url}
+	var pushed, pulled todo.MergeReport
+
+	err := update("sync: "+url, func(list *todo.TodoList) error {
+		var err error
+		if pushed, pulled, err = remote.Sync(list); err != nil {
+			return err
+		}
+
+		list.PruneTombstones(time.Now().Add(-todo.TombstoneRetention))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if pushed.IsEmpty() && pulled.IsEmpty() {
+		_, err := fmt.Fprintf(w, "Already in sync with %s\n", url)
+		return err
+	}
+
+	reports := []struct {
+		title  string
+		report todo.MergeReport
+	}{
+		{"Pushed to", pushed},
+		{"Pulled from", pulled},
+	}
+
+	for _, r := range reports {
+		if r.report.IsEmpty() {
+			continue
+		}
+
+		if _, err := fmt.Fprintf(w, "%s %s:\n", r.title, url); err != nil {
+			return err
+		}
+
+		for _, line := range strings.Split(strings.TrimSuffix(r.report.String(), "\n"), "\n") {
+			if _, err := fmt.Fprintf(w, "  %s\n", line); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Undoes or redoes the last change with step and reports it.
+func undoAction(w io.Writer, args []string, step func(*todo.Journal, *todo.TodoList) (todo.Operation, error), verb string) error {
+	if err := checkArgs(args, 0, 0); err != nil {
+		return err
+	}
+
+	return transact(func(list *todo.TodoList, journal *todo.Journal) error {
+		op, err := step(journal, list)
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprintf(w, "%s %s\n", verb, op.Name)
+		return err
+	})
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"errors"
+
+	"mnishiguchi.com/todo"
+)
+
+var (
+	ErrBlankTask   = todo.ErrBlankTask
+	ErrUnknownCmd  = errors.New("Unknown command")
+	ErrInvalidFlag = errors.New("Invalid flag")
+	ErrMissingArgs = errors.New("Missing arguments")
+	ErrTooManyArgs = errors.New("Too many arguments")
+	ErrInvalidID   = errors.New("Invalid ID")
+	ErrSameList    = errors.New("Cannot move a task to the same list")
+)
+
+// The errors caused by a command line that cannot be run, as opposed to a
+// command that failed.
+var usageErrors = []error{
+	ErrUnknownCmd,
+	ErrInvalidFlag,
+	ErrMissingArgs,
+	ErrTooManyArgs,
+	ErrInvalidID,
+	todo.ErrInvalidListName,
+	todo.ErrInvalidStore,
+}
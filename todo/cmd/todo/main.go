@@ -2,11 +2,14 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"mnishiguchi.com/todo"
 )
@@ -14,6 +17,23 @@ import (
 // Default filename
 var todoFileName = ".todo.json"
 
+// The store that todoFileName is read from and written to
+var todoStore todo.Store
+
+// The directory of named lists, and the name of the current one. The name is
+// empty when TODO_FILENAME selects a file outside the workspace.
+var (
+	workspace    *todo.Workspace
+	todoListName string
+)
+
+// The flags of the first version, which are still accepted but not shown in
+// the usage
+var legacyFlags = map[string]bool{
+	"add": true, "list": true, "complete": true, "delete": true, "filter": true,
+	"sort": true, "ready": true, "undo": true, "redo": true, "history": true,
+}
+
 /*
 ## Examples
 
@@ -22,79 +42,349 @@ var todoFileName = ".todo.json"
     # Build the executable
     go build
 
-    # Display the usage
-    ./todo -h
+    # Display the usage, or the usage of a command
+    ./todo help
+    ./todo help list
 
     # List all tasks
-    ./todo -list
+    ./todo list
 
     # Add a new task from arguments
-    ./todo -add "Go for a walk"
+    ./todo add "Go for a walk"
 
     # Add a new task from STDIN
-    ./todo -add
+    ./todo add
     Study Golang
+
+    # List only what matters, sorted by priority and then due date
+    ./todo list -filter 'not done and tag:backend and due<7d' -sort priority,due
+
+    # Complete, reopen, edit, show or delete tasks by their IDs
+    ./todo done 2 3
+    ./todo undone 3
+    ./todo edit 3 "(A) Study Golang +learning"
+    ./todo show 3
+    ./todo rm 1
+
+    # Repeat a task 30 days after each completion, or every weekday
+    ./todo add "Rotate certs +ops rec:30d"
+    ./todo add "Standup notes due:2026-10-19 rec:+weekday"
+
+    # Break a task into subtasks, and make a task wait on another one
+    ./todo add "Release 1.2"
+    ./todo add -parent 4 "Write the changelog"
+    ./todo block 4 2
+    ./todo list -ready
+    ./todo done -force 4
+
+    # Export the tasks, or import tasks from a todo.txt file or a Markdown checklist
+    ./todo export -format markdown > tasks.md
+    ./todo import todo.txt
+    ./todo import -format markdown - < notes.md
+
+    # List the tasks containing every term
+    ./todo search golang learning
+
+    # Take back the last change, apply it again, or show every change
+    ./todo undo
+    ./todo redo
+    ./todo history
+
+    # Keep the list in an append-only event log instead of a JSON file
+    ./todo -store eventlog add "Write the report"
+    TODO_STORE=kv ./todo list
+
+    # Keep several named lists in the .todo directory, or in TODO_DIR
+    ./todo -list-name work add "Prepare the demo"
+    TODO_LIST=work ./todo list
+    ./todo lists
+    ./todo -list-name work move 1 release-2026.11
+
+The default list is named "default". A .todo.json file from before lists
+existed becomes the default list. TODO_FILENAME still selects a single file
+outside the workspace when no list name is given.
+
+The flags of the first version, such as "-add" and "-list", still work.
+
+## Exit codes
+
+    0: success
+    1: the command failed, such as for an unknown ID
+    2: the command line is invalid, such as an unknown command
 */
 func main() {
-	if os.Getenv("TODO_FILENAME") != "" {
+	// Parse command-line flags. See https://pkg.go.dev/flag
+	argStore := flag.String("store", os.Getenv("TODO_STORE"), "Storage backend: json, eventlog or kv")
+	argListName := flag.String("list-name", os.Getenv("TODO_LIST"), "Name of the list in the workspace (default \""+todo.DefaultListName+"\")")
+	argAdd := flag.Bool("add", false, "Same as the add command")
+	argList := flag.Bool("list", false, "Same as the list command")
+	argComplete := flag.Int("complete", 0, "Same as the done command with the given ID")
+	argDelete := flag.Int("delete", 0, "Same as the rm command with the given ID")
+	argFilter := flag.String("filter", "", "Filter expression for -list")
+	argSort := flag.String("sort", "", "Sort keys for -list")
+	argReady := flag.Bool("ready", false, "Only list the tasks that are ready with -list")
+	argUndo := flag.Bool("undo", false, "Same as the undo command")
+	argRedo := flag.Bool("redo", false, "Same as the redo command")
+	argHistory := flag.Bool("history", false, "Same as the history command")
+	flag.Usage = func() {
+		printUsage(flag.CommandLine.Output())
+	}
+	flag.Parse()
+
+	// Translate the flags of the first version into commands.
+	args := flag.Args()
+	switch {
+	case *argList:
+		args = append([]string{"list", "-filter", *argFilter, "-sort", *argSort,
+			"-ready=" + strconv.FormatBool(*argReady)}, args...)
+	case *argHistory:
+		args = []string{"history"}
+	case *argUndo:
+		args = []string{"undo"}
+	case *argRedo:
+		args = []string{"redo"}
+	case *argComplete > 0:
+		args = []string{"done", strconv.Itoa(*argComplete)}
+	case *argDelete > 0:
+		args = []string{"rm", strconv.Itoa(*argDelete)}
+	case *argAdd:
+		args = append([]string{"add", "--"}, args...)
+	}
+
+	if len(args) == 0 {
+		exit(fmt.Errorf("%w: expected a command", ErrMissingArgs))
+	}
+
+	if args[0] == "help" {
+		exit(help(os.Stdout, args[1:]))
+	}
+
+	cmd, err := findCommand(args[0])
+	if err != nil {
+		exit(err)
+	}
+
+	workspace = &todo.Workspace{Dir: todo.DefaultWorkspaceDir, Kind: *argStore}
+	if os.Getenv("TODO_DIR") != "" {
+		workspace.Dir = os.Getenv("TODO_DIR")
+	}
+
+	if err := selectList(*argListName); err != nil {
+		exit(err)
+	}
+
+	exit(runCommand(cmd, args[1:]))
+}
+
+// Selects the file and the store of the current list.
+func selectList(name string) error {
+	if name == "" && os.Getenv("TODO_FILENAME") != "" {
 		todoFileName = os.Getenv("TODO_FILENAME")
+
+		store, err := todo.NewStore(workspace.Kind, todoFileName)
+		if err != nil {
+			return err
+		}
+		todoStore = store
+
+		return nil
 	}
 
-	// Parse command-line flags. See https://pkg.go.dev/flag
-	argAdd := flag.Bool("add", false, "Add a task to the todo list")
-	argList := flag.Bool("list", false, "List all tasks")
-	argComplete := flag.Int("complete", 0, "Item to be completed")
-	flag.Parse()
+	if name == "" {
+		name = todo.DefaultListName
 
-	// A pointer to an emply todo list
-	list := &todo.TodoList{}
+		if err := workspace.Migrate(todo.DefaultFilename(workspace.Kind)); err != nil {
+			return err
+		}
+	}
 
-	// Read todo items from a file.
-	if err := list.Get(todoFileName); err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+	store, filename, err := workspace.Open(name)
+	if err != nil {
+		return err
 	}
 
-	// Decide what to do based on the number of arguments provided
-	switch {
-	case *argList:
-		// List all todo items
-		fmt.Print(list)
+	todoListName = name
+	todoFileName = filename
+	todoStore = store
 
-	case *argComplete > 0:
-		// Complete a given task
-		if err := list.Complete(*argComplete); err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			os.Exit(1)
+	return nil
+}
+
+// Prints the error, if any, and exits with the matching exit code.
+func exit(err error) {
+	if err == nil {
+		os.Exit(0)
+	}
+
+	fmt.Fprintln(os.Stderr, err)
+
+	for _, usageErr := range usageErrors {
+		if errors.Is(err, usageErr) {
+			fmt.Fprintln(os.Stderr, "Run 'todo help' for usage.")
+			os.Exit(2)
 		}
+	}
+
+	os.Exit(1)
+}
+
+// Prints the usage of the given command, or of the whole CLI without one.
+func help(w io.Writer, args []string) error {
+	if err := checkArgs(args, 0, 1); err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		printUsage(w)
+		return nil
+	}
 
-		// Save the todo list
-		if err := list.Save(todoFileName); err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			os.Exit(1)
+	cmd, err := findCommand(args[0])
+	if err != nil {
+		return err
+	}
+
+	printCommandUsage(w, cmd)
+	return nil
+}
+
+// Prints the commands and the global flags.
+func printUsage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: todo [global flags] COMMAND [flags] [args]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-8s %s\n", cmd.Name, cmd.Short)
+	}
+	fmt.Fprintf(w, "  %-8s %s\n", "help", "Show the usage of a command")
+
+	fmt.Fprintln(w, "\nGlobal flags:")
+	flag.VisitAll(func(f *flag.Flag) {
+		if legacyFlags[f.Name] {
+			return
 		}
 
-	case *argAdd:
-		task, err := getTask(os.Stdin, flag.Args()...)
+		name, usage := flag.UnquoteUsage(f)
+		fmt.Fprintf(w, "  -%s %s\n    \t%s\n", f.Name, name, usage)
+	})
+}
+
+// Prints the todo items matching the filter expression, sorted by the sort
+// keys. Empty arguments list every item in list order. With ready, only the
+// open items whose blockers are done are listed.
+func listTasks(w io.Writer, filterExpr, sortSpec string, ready bool) error {
+	filter, err := todo.ParseFilter(filterExpr, time.Now())
+	if err != nil {
+		return err
+	}
+
+	// A pointer to an empty todo list
+	list := &todo.TodoList{}
+
+	// Read todo items from the store.
+	if err := todoStore.Load(list); err != nil {
+		return err
+	}
+
+	items := list.Select(func(item todo.TodoItem) bool {
+		return filter(item) && (!ready || list.IsReady(item))
+	})
+
+	if sortSpec != "" {
+		less, err := todo.ParseSort(sortSpec)
 		if err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			os.Exit(1)
+			return err
 		}
+		todo.SortItems(items, less)
+	}
 
-		// Add a new task
-		list.Add(task)
+	_, err = fmt.Fprint(w, &todo.TodoList{Items: items})
+	return err
+}
 
-		// Save the todo list
-		if err := list.Save(todoFileName); err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			os.Exit(1)
-		}
+// Applies the change to the list and records it in the journal under the
+// given name, so that it can be undone.
+func update(name string, change func(list *todo.TodoList) error) error {
+	return transact(func(list *todo.TodoList, journal *todo.Journal) error {
+		return journal.Track(name, list, change)
+	})
+}
+
+// Loads the list and its journal, applies the change and saves both while
+// holding the lock, so that concurrent invocations do not lose each other's
+// updates. The changed fields are stamped for syncing.
+func transact(change func(list *todo.TodoList, journal *todo.Journal) error) error {
+	lock, err := todo.Lock(todoFileName)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	list, journal, err := load(todoStore, todoFileName)
+	if err != nil {
+		return err
+	}
+
+	before, err := list.Clone()
+	if err != nil {
+		return err
+	}
+
+	if err := change(list, journal); err != nil {
+		return err
+	}
+	list.Stamp(before, time.Now())
+
+	return save(todoStore, todoFileName, list, journal)
+}
+
+// Loads a list from its store along with its journal.
+func load(store todo.Store, filename string) (*todo.TodoList, *todo.Journal, error) {
+	list := &todo.TodoList{}
+	if err := store.Load(list); err != nil {
+		return nil, nil, err
+	}
+
+	journal := &todo.Journal{}
+	if err := journal.Get(todo.JournalFilename(filename)); err != nil {
+		return nil, nil, err
+	}
 
-	default:
-		// Invalid flag provided
-		fmt.Fprintln(os.Stderr, "Invalid option")
-		os.Exit(1)
+	return list, journal, nil
+}
+
+// Saves a list to its store along with its journal.
+func save(store todo.Store, filename string, list *todo.TodoList, journal *todo.Journal) error {
+	if err := store.Save(list); err != nil {
+		return err
+	}
+
+	return journal.Save(todo.JournalFilename(filename))
+}
+
+// Rewrites the list file of the store to drop its history, holding the lock.
+func compact(compacter todo.Compacter, store todo.Store, filename string) error {
+	lock, err := todo.Lock(filename)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	list := &todo.TodoList{}
+	if err := store.Load(list); err != nil {
+		return err
 	}
+
+	return compacter.Compact(list)
+}
+
+// Prints the changes recorded in the journal.
+func showHistory(w io.Writer) error {
+	journal := &todo.Journal{}
+	if err := journal.Get(todo.JournalFilename(todoFileName)); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprint(w, journal)
+	return err
 }
 
 // Get the task from either arguments or STDIN.
@@ -113,7 +403,7 @@ func getTask(r io.Reader, varargs ...string) (string, error) {
 	}
 
 	if len(s.Text()) == 0 {
-		return "", fmt.Errorf("Task cannot be blank")
+		return "", ErrBlankTask
 	}
 
 	return s.Text(), nil
@@ -1,13 +1,22 @@
 package main_test
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"mnishiguchi.com/todo"
 )
 
 var binName = "todo"
@@ -46,6 +55,9 @@ func TestMain(m *testing.M) {
 	// Delete files that are used in our tests.
 	os.Remove(binName)
 	os.Remove(fileName)
+	os.Remove(fileName + ".lock")
+	os.Remove(fileName + ".journal")
+	os.Remove(fileName + ".archive")
 
 	os.Exit(result)
 }
@@ -97,6 +109,582 @@ func TestTodoCLI(t *testing.T) {
 			t.Errorf("Expected %q, got %q instead\n", expected, string(cmdOutput))
 		}
 	})
+
+	// Delete a task by ID; the IDs of the other tasks do not change
+	t.Run("DeleteTask", func(t *testing.T) {
+		cmd := exec.Command(cmdPath, "-delete", "1")
+
+		if err := cmd.Run(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	// Complete a task by ID
+	t.Run("CompleteTask", func(t *testing.T) {
+		cmd := exec.Command(cmdPath, "-complete", "2")
+
+		if err := cmd.Run(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	// List the remaining task
+	t.Run("ListTasksAfterDelete", func(t *testing.T) {
+		cmd := exec.Command(cmdPath, "-list")
+		cmdOutput, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := fmt.Sprintf("[X] 2: %s\n", taskName2)
+
+		if expected != string(cmdOutput) {
+			t.Errorf("Expected %q, got %q instead\n", expected, string(cmdOutput))
+		}
+	})
+
+	// Filter and sort the list
+	t.Run("ListFilteredSorted", func(t *testing.T) {
+		for _, task := range []string{"(B) Write docs +docs", "(A) Fix bug +backend"} {
+			if err := exec.Command(cmdPath, "-add", task).Run(); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		cmd := exec.Command(cmdPath, "-list", "-filter", "not done", "-sort", "priority")
+		cmdOutput, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := "[ ] 4: (A) Fix bug +backend\n[ ] 3: (B) Write docs +docs\n"
+
+		if expected != string(cmdOutput) {
+			t.Errorf("Expected %q, got %q instead\n", expected, string(cmdOutput))
+		}
+	})
+
+	// An invalid filter is an error
+	t.Run("ListInvalidFilter", func(t *testing.T) {
+		cmd := exec.Command(cmdPath, "-list", "-filter", "done and")
+
+		if err := cmd.Run(); err == nil {
+			t.Errorf("Expected an error for an invalid filter")
+		}
+	})
+
+	// An unknown store is an error
+	t.Run("InvalidStore", func(t *testing.T) {
+		cmd := exec.Command(cmdPath, "-store", "sqlite", "-list")
+
+		if err := cmd.Run(); err == nil {
+			t.Errorf("Expected an error for an unknown store")
+		}
+	})
+
+	// A deleted ID cannot be completed
+	t.Run("CompleteDeletedTask", func(t *testing.T) {
+		cmd := exec.Command(cmdPath, "-complete", "1")
+
+		if err := cmd.Run(); err == nil {
+			t.Errorf("Expected an error completing a deleted task")
+		}
+	})
+
+	// Undo back to before the deletion; the deleted task comes back
+	t.Run("Undo", func(t *testing.T) {
+		for i := 0; i < 4; i++ {
+			if err := exec.Command(cmdPath, "-undo").Run(); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		cmd := exec.Command(cmdPath, "-list")
+		cmdOutput, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := fmt.Sprintf("[ ] 1: %s\n[ ] 2: %s\n", taskName1, taskName2)
+
+		if expected != string(cmdOutput) {
+			t.Errorf("Expected %q, got %q instead\n", expected, string(cmdOutput))
+		}
+	})
+
+	// Redo the deletion
+	t.Run("Redo", func(t *testing.T) {
+		if err := exec.Command(cmdPath, "-redo").Run(); err != nil {
+			t.Fatal(err)
+		}
+
+		cmd := exec.Command(cmdPath, "-list")
+		cmdOutput, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := fmt.Sprintf("[ ] 2: %s\n", taskName2)
+
+		if expected != string(cmdOutput) {
+			t.Errorf("Expected %q, got %q instead\n", expected, string(cmdOutput))
+		}
+	})
+
+	// Show the history with the undone changes marked
+	t.Run("History", func(t *testing.T) {
+		cmd := exec.Command(cmdPath, "-history")
+		cmdOutput, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		lines := strings.Split(strings.TrimSpace(string(cmdOutput)), "\n")
+		if len(lines) != 6 {
+			t.Fatalf("Expected 6 operations, got %q instead", string(cmdOutput))
+		}
+
+		if !strings.HasSuffix(lines[2], " rm: 1") {
+			t.Errorf("Expected the redone delete, got %q instead", lines[2])
+		}
+
+		if !strings.HasSuffix(lines[3], " done: 2 (undone)") {
+			t.Errorf("Expected the undone completion, got %q instead", lines[3])
+		}
+	})
+}
+
+// Runs the CLI with the environment and STDIN, returning its output and exit
+// code.
+func runCLI(t *testing.T, env []string, stdin string, args ...string) (string, int) {
+	t.Helper()
+
+	cmdPath, err := findExecutable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(cmdPath, args...)
+	cmd.Env = env
+	cmd.Stdin = strings.NewReader(stdin)
+
+	out, err := cmd.CombinedOutput()
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return string(out), exitErr.ExitCode()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(out), 0
+}
+
+func TestTodoSubcommands(t *testing.T) {
+	// Use a separate data file so that the other tests are not affected.
+	env := append(os.Environ(), "TODO_FILENAME="+filepath.Join(t.TempDir(), "todo.json"))
+
+	testCases := []struct {
+		name         string
+		args         []string
+		stdin        string
+		expectedOut  string
+		partial      bool // expectedOut is only a part of the output
+		expectedCode int
+	}{
+		{name: "AddFromArguments", args: []string{"add", "Write", "docs", "+docs"}},
+		{name: "AddFromSTDIN", args: []string{"add"}, stdin: "(A) Fix bug +backend\n"},
+		{name: "AddBlank", args: []string{"add"}, expectedOut: "Task cannot be blank", partial: true, expectedCode: 1},
+		{name: "AddOnlyTags", args: []string{"add", "+foo", "@home"},
+			expectedOut: "Task cannot be blank: \"+foo @home\"\n", expectedCode: 1},
+		{name: "AddFlagLikeTask", args: []string{"add", "--", "-v", "flag"}},
+		{name: "List", args: []string{"list"},
+			expectedOut: "[ ] 1: Write docs +docs\n[ ] 2: (A) Fix bug +backend\n[ ] 3: -v flag\n"},
+		{name: "ListSorted", args: []string{"list", "-filter", "+backend or +docs", "-sort", "priority"},
+			expectedOut: "[ ] 2: (A) Fix bug +backend\n[ ] 1: Write docs +docs\n"},
+		{name: "ListExtraArgs", args: []string{"list", "extra"}, expectedOut: "Too many arguments", partial: true, expectedCode: 2},
+		{name: "Done", args: []string{"done", "1", "2"}},
+		{name: "Undone", args: []string{"undone", "2"}},
+		{name: "DoneUnknownID", args: []string{"done", "9"}, expectedOut: "TodoItem does not exist: 9", partial: true, expectedCode: 1},
+		{name: "DoneInvalidID", args: []string{"done", "one"}, expectedOut: "Invalid ID", partial: true, expectedCode: 2},
+		{name: "DoneMissingID", args: []string{"done"}, expectedOut: "Missing arguments", partial: true, expectedCode: 2},
+		{name: "EditFromArguments", args: []string{"edit", "1", "Write", "more", "docs", "+docs"}},
+		{name: "EditFromSTDIN", args: []string{"edit", "3"}, stdin: "Check flags @cli\n"},
+		{name: "ListAfterEdit", args: []string{"list"},
+			expectedOut: "[X] 1: Write more docs +docs\n[ ] 2: (A) Fix bug +backend\n[ ] 3: Check flags @cli\n"},
+		{name: "Search", args: []string{"search", "FIX", "backend"},
+			expectedOut: "[ ] 2: (A) Fix bug +backend\n"},
+		{name: "SearchRanked", args: []string{"search", "chek", "flag"},
+			expectedOut: "[ ] 3: Check flags @cli\n"},
+		{name: "SearchMissingTerms", args: []string{"search"}, expectedOut: "Missing arguments", partial: true, expectedCode: 2},
+		{name: "Show", args: []string{"show", "2"},
+			expectedOut: "ID:         2\nTask:       Fix bug\nStatus:     open\nPriority:   A\nProjects:   backend\n", partial: true},
+		{name: "Remove", args: []string{"rm", "3"}},
+		{name: "RemoveAgain", args: []string{"rm", "3"}, expectedOut: "TodoItem does not exist: 3", partial: true, expectedCode: 1},
+		{name: "Undo", args: []string{"undo"}, expectedOut: "Undid rm: 3\n"},
+		{name: "Redo", args: []string{"redo"}, expectedOut: "Redid rm: 3\n"},
+		{name: "RedoNothing", args: []string{"redo"}, expectedOut: "Nothing to redo", partial: true, expectedCode: 1},
+		{name: "History", args: []string{"history"}, expectedOut: "rm: 3\n", partial: true},
+		{name: "AddRecurring", args: []string{"add", "Standup notes due:2026-10-16 rec:+weekday"}},
+		{name: "DoneRecurring", args: []string{"done", "4"}},
+		{name: "ListRecurring", args: []string{"list", "-filter", "standup"},
+			expectedOut: "[X] 4: Standup notes due:2026-10-16 rec:+weekday\n" +
+				"[ ] 5: Standup notes due:2026-10-19 rec:+weekday\n"},
+		{name: "ShowRecurring", args: []string{"show", "5"}, expectedOut: "Recurs:     +weekday\nDone on:    ", partial: true},
+		{name: "AddSubtask", args: []string{"add", "-parent", "2", "Write a regression test"}},
+		{name: "AddSubtaskUnknownParent", args: []string{"add", "-parent", "9", "Orphan"},
+			expectedOut: "TodoItem does not exist: 9", partial: true, expectedCode: 1},
+		{name: "UndoneBlocked", args: []string{"undone", "1"}},
+		{name: "Block", args: []string{"block", "1", "2"}},
+		{name: "BlockCycle", args: []string{"block", "2", "1"},
+			expectedOut: "Dependency cycle: 2 -> 1 -> 2", partial: true, expectedCode: 1},
+		{name: "ListTree", args: []string{"list", "-filter", "+backend or regression or docs"},
+			expectedOut: "[ ] 1: Write more docs +docs\n[ ] 2: (A) Fix bug +backend\n" +
+				"  [ ] 6: Write a regression test\n"},
+		{name: "ListReady", args: []string{"list", "-ready", "-filter", "+backend or regression or docs"},
+			expectedOut: "[ ] 2: (A) Fix bug +backend\n  [ ] 6: Write a regression test\n"},
+		{name: "DoneOpenChildren", args: []string{"done", "2"},
+			expectedOut: "TodoItem has open children", partial: true, expectedCode: 1},
+		{name: "DoneForce", args: []string{"done", "-force", "2"}},
+		{name: "ListReadyAfterDone", args: []string{"list", "-ready", "-filter", "+backend or regression or docs"},
+			expectedOut: "[ ] 1: Write more docs +docs\n"},
+		{name: "LegacyListReady", args: []string{"-list", "-ready", "-filter", "+backend or regression or docs"},
+			expectedOut: "[ ] 1: Write more docs +docs\n"},
+		{name: "Unblock", args: []string{"unblock", "1", "2"}},
+		{name: "ShowSubtask", args: []string{"show", "6"}, expectedOut: "Parent:     2\n", partial: true},
+		{name: "ExportMarkdown", args: []string{"export", "-format", "markdown"},
+			expectedOut: "- [x] (A) Fix bug +backend\n  - [x] Write a regression test\n", partial: true},
+		{name: "ExportInvalidFormat", args: []string{"export", "-format", "yaml"},
+			expectedOut: "Invalid format", partial: true, expectedCode: 1},
+		{name: "ImportMarkdown", args: []string{"import", "-"},
+			stdin:       "# Sprint\n- [ ] (A) Fix bug +backend\n- [ ] Review PR\n  - [x] Read the diff\n",
+			expectedOut: "[ ] 7: Review PR\n  [X] 8: Read the diff\nImported 2 tasks from markdown, skipped 1 duplicates\n"},
+		{name: "ImportMissingFile", args: []string{"import", "missing.txt"},
+			expectedOut: "no such file", partial: true, expectedCode: 1},
+		{name: "Help", args: []string{"help"}, expectedOut: "  search   List the tasks matching every term", partial: true},
+		{name: "HelpCommand", args: []string{"help", "list"}, expectedOut: "Usage: todo [global flags] list [flags]", partial: true},
+		{name: "CommandHelpFlag", args: []string{"list", "-h"}, expectedOut: "-filter string", partial: true},
+		{name: "InvalidFlag", args: []string{"list", "-bogus"}, expectedOut: "Invalid flag", partial: true, expectedCode: 2},
+		{name: "UnknownCommand", args: []string{"bogus"}, expectedOut: "Unknown command: \"bogus\"", partial: true, expectedCode: 2},
+		{name: "NoCommand", args: []string{}, expectedOut: "Missing arguments", partial: true, expectedCode: 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			out, code := runCLI(t, env, tc.stdin, tc.args...)
+
+			if code != tc.expectedCode {
+				t.Fatalf("Expected exit code %d, got %d instead: %q", tc.expectedCode, code, out)
+			}
+
+			if tc.partial {
+				if !strings.Contains(out, tc.expectedOut) {
+					t.Errorf("Expected %q in the output, got %q instead", tc.expectedOut, out)
+				}
+				return
+			}
+
+			if out != tc.expectedOut {
+				t.Errorf("Expected %q, got %q instead", tc.expectedOut, out)
+			}
+		})
+	}
+}
+
+func TestTodoWorkspace(t *testing.T) {
+	// Use a workspace directory instead of the single file of the other tests.
+	env := []string{"TODO_DIR=" + filepath.Join(t.TempDir(), "lists")}
+	for _, v := range os.Environ() {
+		if !strings.HasPrefix(v, "TODO_FILENAME=") {
+			env = append(env, v)
+		}
+	}
+
+	testCases := []struct {
+		name         string
+		args         []string
+		expectedOut  string
+		expectedCode int
+	}{
+		{name: "AddToDefault", args: []string{"add", "Water plants"}},
+		{name: "AddToWork", args: []string{"-list-name", "work", "add", "Prepare the demo"}},
+		{name: "AddSubtaskToWork", args: []string{"-list-name", "work", "add", "-parent", "1", "Write the script"}},
+		{name: "AddMoreToWork", args: []string{"-list-name", "work", "add", "Review PR"}},
+		{name: "DoneInWork", args: []string{"-list-name", "work", "done", "3"}},
+		{name: "Lists", args: []string{"lists"},
+			expectedOut: "* default                 1 open    0 done\n" +
+				"  work                    2 open    1 done\n"},
+		{name: "Move", args: []string{"-list-name", "work", "move", "1", "release-2026.11"},
+			expectedOut: "Moved 1 to release-2026.11 as 1\n"},
+		{name: "ListMoved", args: []string{"-list-name", "release-2026.11", "list"},
+			expectedOut: "[ ] 1: Prepare the demo\n  [ ] 2: Write the script\n"},
+		{name: "ListsAfterMove", args: []string{"-list-name", "work", "lists"},
+			expectedOut: "  default                 1 open    0 done\n" +
+				"  release-2026.11         2 open    0 done\n" +
+				"* work                    0 open    1 done\n"},
+		// The move itself cannot be undone.
+		{name: "UndoAfterMove", args: []string{"-list-name", "work", "undo"},
+			expectedOut: "Undid done: 3\n"},
+		{name: "ListAfterUndo", args: []string{"-list-name", "work", "list"},
+			expectedOut: "[ ] 3: Review PR\n"},
+		{name: "RedoAfterMove", args: []string{"-list-name", "work", "redo"},
+			expectedOut: "Redid done: 3\n"},
+		{name: "MoveToSameList", args: []string{"-list-name", "work", "move", "3", "work"},
+			expectedOut: "Cannot move a task to the same list: work\n", expectedCode: 1},
+		{name: "MoveMissingItem", args: []string{"move", "9", "work"},
+			expectedOut: "TodoItem does not exist: 9\n", expectedCode: 1},
+		{name: "InvalidListName", args: []string{"-list-name", "../work", "list"},
+			expectedOut: "Invalid list name: \"../work\"\nRun 'todo help' for usage.\n", expectedCode: 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			out, code := runCLI(t, env, "", tc.args...)
+
+			if code != tc.expectedCode {
+				t.Fatalf("Expected exit code %d, got %d instead: %q", tc.expectedCode, code, out)
+			}
+
+			if out != tc.expectedOut {
+				t.Errorf("Expected %q, got %q instead", tc.expectedOut, out)
+			}
+		})
+	}
+}
+
+func TestTodoTimeTracking(t *testing.T) {
+	env := append(os.Environ(), "TODO_FILENAME="+filepath.Join(t.TempDir(), "todo.json"))
+
+	testCases := []struct {
+		name         string
+		args         []string
+		expectedOut  string
+		partial      bool // expectedOut is only a part of the output
+		expectedCode int
+	}{
+		{name: "Add", args: []string{"add", "Ship release +backend"}},
+		{name: "AddMore", args: []string{"add", "Pay bills"}},
+		{name: "Start", args: []string{"start", "1"}, expectedOut: "Started 1: Ship release\n"},
+		{name: "StartOther", args: []string{"start", "2"},
+			expectedOut: "Stopped 1: Ship release after 0h00m\nStarted 2: Pay bills\n"},
+		{name: "Stop", args: []string{"stop", "2"}, expectedOut: "Stopped 2: Pay bills after 0h00m\n"},
+		{name: "StopAgain", args: []string{"stop", "2"}, expectedOut: "TodoItem is not started: 2\n", expectedCode: 1},
+		{name: "Show", args: []string{"show", "1"}, expectedOut: "Time spent: 0h00m\n", partial: true},
+		{name: "Report", args: []string{"report"}, expectedOut: "\nTags\n     0h00m  +backend\n\nTotal     0h00m\n", partial: true},
+		{name: "ReportCSV", args: []string{"report", "-since", "today", "-format", "csv"},
+			expectedOut: "tag,,+backend,0\ntotal,,,0\n", partial: true},
+		{name: "ReportInvalidSince", args: []string{"report", "-since", "bogus"},
+			expectedOut: "Invalid start date: \"bogus\"\n", expectedCode: 1},
+		{name: "ReportInvalidFormat", args: []string{"report", "-format", "yaml"},
+			expectedOut: "Invalid format: \"yaml\"\n", expectedCode: 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			out, code := runCLI(t, env, "", tc.args...)
+
+			if code != tc.expectedCode {
+				t.Fatalf("Expected exit code %d, got %d instead: %q", tc.expectedCode, code, out)
+			}
+
+			if tc.partial && !strings.Contains(out, tc.expectedOut) || !tc.partial && out != tc.expectedOut {
+				t.Errorf("Expected %q, got %q instead", tc.expectedOut, out)
+			}
+		})
+	}
+}
+
+func TestTodoArchive(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "todo.json")
+	env := append(os.Environ(), "TODO_FILENAME="+fileName)
+
+	testCases := []struct {
+		name         string
+		args         []string
+		expectedOut  string
+		partial      bool // expectedOut is only a part of the output
+		expectedCode int
+	}{
+		{name: "Add", args: []string{"add", "Ship release +backend"}, partial: true},
+		{name: "AddMore", args: []string{"add", "Pay bills"}, partial: true},
+		{name: "AddLast", args: []string{"add", "Fix login bug"}, partial: true},
+		{name: "Done", args: []string{"done", "1"}, partial: true},
+		{name: "DoneMore", args: []string{"done", "2"}, partial: true},
+		{name: "ArchiveNothing", args: []string{"archive"},
+			expectedOut: "Archived 0 tasks to " + fileName + ".archive\n"},
+		{name: "Archive", args: []string{"archive", "-older-than", "0d"},
+			expectedOut: "Archived 2 tasks to " + fileName + ".archive\n"},
+		{name: "List", args: []string{"list"}, expectedOut: "[ ] 3: Fix login bug\n"},
+		{name: "UndoSkipsArchived", args: []string{"undo"}, expectedOut: "Undid add: Fix login bug\n"},
+		{name: "Redo", args: []string{"redo"}, expectedOut: "Redid add: Fix login bug\n"},
+		{name: "Search", args: []string{"search", "bills"}, expectedOut: ""},
+		{name: "SearchArchive", args: []string{"search", "-archive", "bills"}, expectedOut: "Pay bills", partial: true},
+		{name: "AddAfterArchive", args: []string{"add", "Book the room"}, partial: true},
+		{name: "ShowNewID", args: []string{"show", "4"}, expectedOut: "Book the room", partial: true},
+		{name: "Stats", args: []string{"stats"}, expectedOut: "Tasks:       4\n" +
+			"Open:        2\n" +
+			"Overdue:     0\n" +
+			"Done:        2\n" +
+			"Archived:    2\n" +
+			"Completion:  50.0%\n", partial: true},
+		{name: "ArchiveInvalid", args: []string{"archive", "-older-than", "soon"},
+			expectedOut: "Invalid start date: \"soon\"\n", expectedCode: 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			out, code := runCLI(t, env, "", tc.args...)
+
+			if code != tc.expectedCode {
+				t.Fatalf("Expected exit code %d, got %d instead: %q", tc.expectedCode, code, out)
+			}
+
+			if tc.partial && !strings.Contains(out, tc.expectedOut) || !tc.partial && out != tc.expectedOut {
+				t.Errorf("Expected %q, got %q instead", tc.expectedOut, out)
+			}
+		})
+	}
+}
+
+func TestTodoSync(t *testing.T) {
+	// Stand in for todo_server with a list that merges what it receives.
+	serverList := &todo.TodoList{}
+	serverList.Add("Review PR")
+
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		state := todo.SyncState{}
+		if req.URL.Path != todo.SyncPath || json.NewDecoder(req.Body).Decode(&state) != nil {
+			http.Error(w, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		report := serverList.Merge(state.Items, state.Deleted)
+		json.NewEncoder(w).Encode(todo.SyncReply{
+			State:  todo.SyncState{Items: serverList.Items, Deleted: serverList.Deleted},
+			Report: report,
+		})
+	}))
+	defer server.Close()
+
+	env := append(os.Environ(), "TODO_FILENAME="+filepath.Join(t.TempDir(), "todo.json"), "TODO_REMOTE=")
+
+	testCases := []struct {
+		name         string
+		args         []string
+		serverChange func(l *todo.TodoList) error
+		expectedOut  string
+		expectedCode int
+	}{
+		{name: "Add", args: []string{"add", "Ship release"}},
+		{name: "Sync", args: []string{"sync", "-remote", server.URL},
+			expectedOut: "Pushed to " + server.URL + ":\n  added: Ship release\n" +
+				"Pulled from " + server.URL + ":\n  added: Review PR\n"},
+		{name: "SyncAgain", args: []string{"sync", "-remote", server.URL},
+			expectedOut: "Already in sync with " + server.URL + "\n"},
+		{name: "SyncServerChange", args: []string{"sync", "-remote", server.URL},
+			serverChange: func(l *todo.TodoList) error { return l.Complete(1) },
+			expectedOut:  "Pulled from " + server.URL + ":\n  updated: Review PR (done)\n"},
+		{name: "List", args: []string{"list", "-filter", "done"},
+			expectedOut: "[X] 2: Review PR\n"},
+		{name: "Delete", args: []string{"rm", "1"}},
+		{name: "SyncDelete", args: []string{"sync", "-remote", server.URL},
+			expectedOut: "Pushed to " + server.URL + ":\n  deleted: Ship release\n"},
+		{name: "UndoDelete", args: []string{"undo"}, expectedOut: "Undid rm: 1\n"},
+		{name: "SyncUndoDelete", args: []string{"sync", "-remote", server.URL},
+			expectedOut: "Pushed to " + server.URL + ":\n  added: Ship release\n"},
+		{name: "ListRestored", args: []string{"list"},
+			expectedOut: "[ ] 1: Ship release\n[X] 2: Review PR\n"},
+		{name: "SyncMissingRemote", args: []string{"sync"},
+			expectedOut: "Missing arguments: -remote\nRun 'todo help' for usage.\n", expectedCode: 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.serverChange != nil {
+				mu.Lock()
+				before, err := serverList.Clone()
+				if err == nil {
+					err = tc.serverChange(serverList)
+				}
+				serverList.Stamp(before, time.Now())
+				mu.Unlock()
+
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			out, code := runCLI(t, env, "", tc.args...)
+
+			if code != tc.expectedCode {
+				t.Fatalf("Expected exit code %d, got %d instead: %q", tc.expectedCode, code, out)
+			}
+
+			if out != tc.expectedOut {
+				t.Errorf("Expected %q, got %q instead", tc.expectedOut, out)
+			}
+		})
+	}
+}
+
+// Many processes adding tasks at the same time must not lose any of them,
+// whichever store keeps the list.
+func TestTodoCLIConcurrentAdd(t *testing.T) {
+	for _, store := range []string{"json", "eventlog", "kv"} {
+		t.Run(store, func(t *testing.T) {
+			testConcurrentAdd(t, store)
+		})
+	}
+}
+
+func testConcurrentAdd(t *testing.T, store string) {
+	cmdPath, err := findExecutable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Use a separate data file so that the other tests are not affected.
+	env := append(os.Environ(),
+		"TODO_STORE="+store,
+		"TODO_FILENAME="+filepath.Join(t.TempDir(), "todo."+store),
+	)
+
+	const numTasks = 30
+	errs := make(chan error, numTasks)
+
+	for i := 1; i <= numTasks; i++ {
+		cmd := exec.Command(cmdPath, "-add", fmt.Sprintf("concurrent task %d", i))
+		cmd.Env = env
+
+		go func() {
+			errs <- cmd.Run()
+		}()
+	}
+
+	for i := 0; i < numTasks; i++ {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := exec.Command(cmdPath, "-list")
+	cmd.Env = env
+	cmdOutput, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 1; i <= numTasks; i++ {
+		task := fmt.Sprintf("concurrent task %d\n", i)
+		if !strings.Contains(string(cmdOutput), task) {
+			t.Errorf("Expected %q in the list, got %q instead", task, string(cmdOutput))
+		}
+	}
+
+	if lines := strings.Count(string(cmdOutput), "\n"); lines != numTasks {
+		t.Errorf("Expected %d tasks, got %d instead", numTasks, lines)
+	}
 }
 
 // Find the executable that is compiled in TestMain()
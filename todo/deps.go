@@ -0,0 +1,210 @@
+package todo
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Reports a change that would make items wait on each other forever. IDs is
+// the path of the cycle, starting and ending with the same ID.
+type CycleError struct {
+	IDs []int
+}
+
+func (e *CycleError) Error() string {
+	ids := []string{}
+	for _, id := range e.IDs {
+		ids = append(ids, fmt.Sprint(id))
+	}
+
+	return fmt.Sprintf("%v: %s", ErrDependencyCycle, strings.Join(ids, " -> "))
+}
+
+// Makes errors.Is(err, ErrDependencyCycle) true for a CycleError.
+func (e *CycleError) Unwrap() error {
+	return ErrDependencyCycle
+}
+
+// Makes the item a child of the parent, or a top-level item when parentID is
+// zero. A parent cannot be completed without -force while a child is open, so
+// an item cannot become a child of one of its own dependencies.
+func (l *TodoList) SetParent(id, parentID int) error {
+	index, err := l.indexOf(id)
+	if err != nil {
+		return err
+	}
+
+	if parentID != 0 {
+		if _, err := l.indexOf(parentID); err != nil {
+			return err
+		}
+
+		// The parent is going to wait on the item.
+		if err := l.checkCycle(parentID, id); err != nil {
+			return err
+		}
+	}
+
+	l.Items[index].ParentID = parentID
+
+	return nil
+}
+
+// Makes the item wait until the blocker is completed.
+func (l *TodoList) Block(id, blockerID int) error {
+	index, err := l.indexOf(id)
+	if err != nil {
+		return err
+	}
+
+	if _, err := l.indexOf(blockerID); err != nil {
+		return err
+	}
+
+	if err := l.checkCycle(id, blockerID); err != nil {
+		return err
+	}
+
+	l.Items[index].BlockedBy = appendUniqueID(l.Items[index].BlockedBy, blockerID)
+
+	return nil
+}
+
+// Removes the link that makes the item wait on the blocker.
+func (l *TodoList) Unblock(id, blockerID int) error {
+	index, err := l.indexOf(id)
+	if err != nil {
+		return err
+	}
+
+	l.Items[index].BlockedBy = removeID(l.Items[index].BlockedBy, blockerID)
+
+	return nil
+}
+
+// Reports whether the item is open and every item blocking it is completed.
+// Blockers that were deleted no longer block.
+func (l *TodoList) IsReady(item TodoItem) bool {
+	if item.Done {
+		return false
+	}
+
+	for _, id := range item.BlockedBy {
+		if blocker, err := l.Find(id); err == nil && !blocker.Done {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Returns the IDs of the open descendants of the item.
+func (l *TodoList) openDescendants(id int) []int {
+	ids := []int{}
+
+	for _, item := range l.Items {
+		if item.ParentID != id {
+			continue
+		}
+
+		if !item.Done {
+			ids = append(ids, item.ID)
+		}
+		ids = append(ids, l.openDescendants(item.ID)...)
+	}
+
+	return ids
+}
+
+// Returns an error if making the item wait on the other one would close a
+// cycle, which is when the other one already waits on the item directly or
+// indirectly. A parent waits on its children and an item waits on its
+// blockers.
+func (l *TodoList) checkCycle(id, otherID int) error {
+	if id == otherID {
+		return &CycleError{IDs: []int{id, id}}
+	}
+
+	if path := l.waitPath(otherID, id, map[int]bool{}); path != nil {
+		return &CycleError{IDs: append([]int{id}, path...)}
+	}
+
+	return nil
+}
+
+// Returns the path of IDs from one item to another following what each item
+// waits on, or nil when there is none.
+func (l *TodoList) waitPath(from, to int, visited map[int]bool) []int {
+	if from == to {
+		return []int{to}
+	}
+
+	if visited[from] {
+		return nil
+	}
+	visited[from] = true
+
+	for _, next := range l.waitsOn(from) {
+		if path := l.waitPath(next, to, visited); path != nil {
+			return append([]int{from}, path...)
+		}
+	}
+
+	return nil
+}
+
+// Returns the IDs of the items that the item waits on: its children and its
+// blockers.
+func (l *TodoList) waitsOn(id int) []int {
+	ids := []int{}
+
+	for _, item := range l.Items {
+		if item.ParentID == id {
+			ids = append(ids, item.ID)
+		}
+
+		if item.ID == id {
+			ids = append(ids, item.BlockedBy...)
+		}
+	}
+
+	return ids
+}
+
+// Unlinks a deleted item: its children move up to its parent and it no longer
+// blocks anything.
+func (l *TodoList) unlink(deleted TodoItem) {
+	for i := range l.Items {
+		if l.Items[i].ParentID == deleted.ID {
+			l.Items[i].ParentID = deleted.ParentID
+		}
+
+		l.Items[i].BlockedBy = removeID(l.Items[i].BlockedBy, deleted.ID)
+	}
+}
+
+func appendUniqueID(ids []int, id int) []int {
+	for _, v := range ids {
+		if v == id {
+			return ids
+		}
+	}
+
+	return append(ids, id)
+}
+
+// Returns a copy of the IDs without id, or nil when none is left.
+func removeID(ids []int, id int) []int {
+	kept := []int{}
+	for _, v := range ids {
+		if v != id {
+			kept = append(kept, v)
+		}
+	}
+
+	if len(kept) == 0 {
+		return nil
+	}
+
+	return kept
+}
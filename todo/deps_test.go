@@ -0,0 +1,161 @@
+package todo_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"mnishiguchi.com/todo"
+)
+
+// Returns a list of tasks 1 to n.
+func newListOf(n int) *todo.TodoList {
+	list := &todo.TodoList{}
+	for i := 1; i <= n; i++ {
+		list.Add("Task " + string(rune('0'+i)))
+	}
+
+	return list
+}
+
+func TestStringTree(t *testing.T) {
+	list := newListOf(4)
+
+	for _, link := range [][2]int{{2, 1}, {3, 2}, {4, 0}} {
+		if err := list.SetParent(link[0], link[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := "[ ] 1: Task 1\n" +
+		"  [ ] 2: Task 2\n" +
+		"    [ ] 3: Task 3\n" +
+		"[ ] 4: Task 4\n"
+
+	if res := list.String(); res != expected {
+		t.Errorf("Expected %q, got %q instead", expected, res)
+	}
+
+	// A child whose parent is not listed is printed at the top level.
+	subset := todo.TodoList{Items: list.Items[2:]}
+	expected = "[ ] 3: Task 3\n[ ] 4: Task 4\n"
+
+	if res := subset.String(); res != expected {
+		t.Errorf("Expected %q, got %q instead", expected, res)
+	}
+}
+
+func TestCompleteWithOpenChildren(t *testing.T) {
+	list := newListOf(3)
+	list.SetParent(2, 1)
+	list.SetParent(3, 2)
+
+	if err := list.Complete(1); !errors.Is(err, todo.ErrOpenChildren) {
+		t.Fatalf("Expected %q, got %q instead", todo.ErrOpenChildren, err)
+	}
+
+	if err := list.ForceComplete(1); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, item := range list.Items {
+		if !item.Done {
+			t.Errorf("Expected item %d to be completed", item.ID)
+		}
+	}
+}
+
+func TestReady(t *testing.T) {
+	list := newListOf(3)
+
+	if err := list.Block(3, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := list.Block(3, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	ready := func() []int {
+		ids := []int{}
+		for _, item := range list.Select(list.IsReady) {
+			ids = append(ids, item.ID)
+		}
+		return ids
+	}
+
+	if res := ready(); !reflect.DeepEqual(res, []int{1, 2}) {
+		t.Errorf("Expected [1 2], got %v instead", res)
+	}
+
+	list.Complete(1)
+	list.Delete(2)
+
+	if res := ready(); !reflect.DeepEqual(res, []int{3}) {
+		t.Errorf("Expected [3], got %v instead", res)
+	}
+}
+
+func TestDependencyCycle(t *testing.T) {
+	testCases := []struct {
+		name     string
+		parents  [][2]int // child, parent
+		blocks   [][2]int // item, blocker
+		expected []int
+	}{
+		{name: "SelfBlock", blocks: [][2]int{{1, 1}}, expected: []int{1, 1}},
+		{name: "Blockers", blocks: [][2]int{{1, 2}, {2, 3}, {3, 1}}, expected: []int{3, 1, 2, 3}},
+		{name: "Parents", parents: [][2]int{{2, 1}, {3, 2}, {1, 3}}, expected: []int{3, 1, 2, 3}},
+		{name: "ChildBlockedByParent", parents: [][2]int{{2, 1}}, blocks: [][2]int{{2, 1}}, expected: []int{2, 1, 2}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			list := newListOf(3)
+
+			var err error
+			for _, p := range tc.parents {
+				if err = list.SetParent(p[0], p[1]); err != nil {
+					break
+				}
+			}
+			for _, b := range tc.blocks {
+				if err != nil {
+					break
+				}
+				err = list.Block(b[0], b[1])
+			}
+
+			var cycleErr *todo.CycleError
+			if !errors.As(err, &cycleErr) {
+				t.Fatalf("Expected a cycle error, got %v instead", err)
+			}
+
+			if !errors.Is(err, todo.ErrDependencyCycle) {
+				t.Errorf("Expected %q, got %q instead", todo.ErrDependencyCycle, err)
+			}
+
+			if !reflect.DeepEqual(cycleErr.IDs, tc.expected) {
+				t.Errorf("Expected %v, got %v instead", tc.expected, cycleErr.IDs)
+			}
+		})
+	}
+}
+
+func TestDeleteUnlinks(t *testing.T) {
+	list := newListOf(3)
+	list.SetParent(2, 1)
+	list.SetParent(3, 2)
+	list.Block(1, 2)
+
+	if err := list.Delete(2); err != nil {
+		t.Fatal(err)
+	}
+
+	if list.Items[0].BlockedBy != nil {
+		t.Errorf("Expected no blockers, got %v instead", list.Items[0].BlockedBy)
+	}
+
+	if list.Items[1].ParentID != 1 {
+		t.Errorf("Expected item 3 to move up to item 1, got parent %d instead", list.Items[1].ParentID)
+	}
+}
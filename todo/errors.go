@@ -0,0 +1,26 @@
+package todo
+
+import "errors"
+
+var (
+	ErrItemNotFound      = errors.New("TodoItem does not exist")
+	ErrBlankTask         = errors.New("Task cannot be blank")
+	ErrInvalidFilter     = errors.New("Invalid filter")
+	ErrInvalidSortKey    = errors.New("Invalid sort key")
+	ErrInvalidStore      = errors.New("Invalid store")
+	ErrInvalidStoreFile  = errors.New("Not a valid store file")
+	ErrNothingToUndo     = errors.New("Nothing to undo")
+	ErrNothingToRedo     = errors.New("Nothing to redo")
+	ErrItemExists        = errors.New("TodoItem already exists")
+	ErrInvalidRecurrence = errors.New("Invalid recurrence rule")
+	ErrOpenChildren      = errors.New("TodoItem has open children")
+	ErrDependencyCycle   = errors.New("Dependency cycle")
+	ErrInvalidFormat     = errors.New("Invalid format")
+	ErrInvalidImport     = errors.New("Cannot import the data")
+	ErrInvalidListName   = errors.New("Invalid list name")
+	ErrSync              = errors.New("Cannot sync with the server")
+	ErrItemDone          = errors.New("TodoItem is completed")
+	ErrSessionRunning    = errors.New("TodoItem is already started")
+	ErrNoSession         = errors.New("TodoItem is not started")
+	ErrInvalidSince      = errors.New("Invalid start date")
+)
@@ -0,0 +1,93 @@
+package todo
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Writes data to a temporary file, syncs it and renames it to filename. The
+// rename is atomic on POSIX systems, so readers see either the old or the new
+// content.
+func writeFileAtomic(filename string, data []byte, perm os.FileMode) error {
+	dir, base := filepath.Split(filename)
+	if dir == "" {
+		dir = "."
+	}
+
+	tmp, err := os.CreateTemp(dir, base+".tmp-*")
+	if err != nil {
+		return err
+	}
+
+	// Remove the temporary file unless it was renamed successfully.
+	renamed := false
+	defer func() {
+		if !renamed {
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), filename); err != nil {
+		return err
+	}
+	renamed = true
+
+	// Sync the directory so that the rename itself survives a crash. Not every
+	// platform supports this, so errors are ignored.
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+
+	return nil
+}
+
+// An advisory lock guarding a read-modify-write cycle of a file.
+type FileLock struct {
+	file *os.File
+}
+
+// Acquires an exclusive lock for the provided file name, waiting until any
+// other process holding it releases it. The lock is held on a separate
+// ".lock" file so that renaming the data file does not affect it.
+func Lock(filename string) (*FileLock, error) {
+	f, err := os.OpenFile(filename+".lock", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &FileLock{file: f}, nil
+}
+
+// Releases the lock.
+func (fl *FileLock) Unlock() error {
+	if err := unlockFile(fl.file); err != nil {
+		fl.file.Close()
+		return err
+	}
+
+	return fl.file.Close()
+}
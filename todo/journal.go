@@ -0,0 +1,294 @@
+package todo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// The number of operations a journal keeps; older ones can no longer be undone.
+const journalLimit = 100
+
+// A change to a single item. Before is nil when the item was added and After
+// is nil when it was deleted. Index is the position of the item in the list
+// that contains it: the list after the operation for an added item, and the
+// list before it otherwise.
+type Change struct {
+	Before *TodoItem `json:",omitempty"`
+	After  *TodoItem `json:",omitempty"`
+	Index  int
+}
+
+// A mutation of the list, recorded as the changes it made to its items.
+type Operation struct {
+	Name         string // such as "add: Go for a walk" or "delete: 3"
+	Time         time.Time
+	Changes      []Change
+	NextIDBefore int
+	NextIDAfter  int
+}
+
+// The history of operations on a list. The operations before Cursor are
+// applied to the list; the ones after it were undone and can be redone.
+type Journal struct {
+	Operations []Operation
+	Cursor     int
+}
+
+// Returns the name of the journal file kept next to the list file.
+func JournalFilename(filename string) string {
+	return filename + ".journal"
+}
+
+// Applies the change to the list and records what it did as an operation with
+// the given name. Recording a new operation discards the ones that were undone.
+func (j *Journal) Track(name string, l *TodoList, change func(*TodoList) error) error {
+	before, err := copyList(l)
+	if err != nil {
+		return err
+	}
+
+	if err := change(l); err != nil {
+		return err
+	}
+
+	changes, err := diffItems(before.Items, l.Items)
+	if err != nil {
+		return err
+	}
+
+	if len(changes) == 0 && before.NextID == l.NextID {
+		return nil
+	}
+
+	op := Operation{
+		Name:         name,
+		Time:         time.Now(),
+		Changes:      changes,
+		NextIDBefore: before.NextID,
+		NextIDAfter:  l.NextID,
+	}
+
+	j.Operations = append(j.Operations[:j.Cursor], op)
+	if len(j.Operations) > journalLimit {
+		j.Operations = j.Operations[len(j.Operations)-journalLimit:]
+	}
+	j.Cursor = len(j.Operations)
+
+	return nil
+}
+
+// Reverts the last applied operation and returns it.
+func (j *Journal) Undo(l *TodoList) (Operation, error) {
+	if j.Cursor == 0 {
+		return Operation{}, ErrNothingToUndo
+	}
+
+	op := j.Operations[j.Cursor-1]
+
+	// Revert to the items before the operation: remove the added items,
+	// restore the changed ones and put the deleted ones back in place.
+	reverted := []Change{}
+	for _, c := range op.Changes {
+		reverted = append(reverted, Change{Before: c.After, After: c.Before, Index: c.Index})
+	}
+
+	if err := applyChanges(l, reverted); err != nil {
+		return Operation{}, err
+	}
+	// The next ID is never lowered, so that the IDs of the undone items are
+	// not given to new ones.
+	if op.NextIDAfter > l.NextID {
+		l.NextID = op.NextIDAfter
+	}
+	j.Cursor--
+
+	return op, nil
+}
+
+// Applies the last undone operation again and returns it.
+func (j *Journal) Redo(l *TodoList) (Operation, error) {
+	if j.Cursor == len(j.Operations) {
+		return Operation{}, ErrNothingToRedo
+	}
+
+	op := j.Operations[j.Cursor]
+
+	if err := applyChanges(l, op.Changes); err != nil {
+		return Operation{}, err
+	}
+	if op.NextIDAfter > l.NextID {
+		l.NextID = op.NextIDAfter
+	}
+	j.Cursor++
+
+	return op, nil
+}
+
+// Drops the operations that changed any of the items with the given IDs, such
+// as items moved out of the list, since they can no longer be undone or redone.
+func (j *Journal) Forget(ids ...int) {
+	forgotten := map[int]bool{}
+	for _, id := range ids {
+		forgotten[id] = true
+	}
+
+	kept := []Operation{}
+	cursor := j.Cursor
+
+	for i, op := range j.Operations {
+		touches := false
+		for _, c := range op.Changes {
+			if c.Before != nil && forgotten[c.Before.ID] || c.After != nil && forgotten[c.After.ID] {
+				touches = true
+				break
+			}
+		}
+
+		if !touches {
+			kept = append(kept, op)
+		} else if i < j.Cursor {
+			cursor--
+		}
+	}
+
+	j.Operations = kept
+	j.Cursor = cursor
+}
+
+// Saves the journal as JSON using the provided file name.
+func (j *Journal) Save(filename string) error {
+	data, err := json.Marshal(j)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(filename, data, 0644)
+}
+
+// Reads the journal from the provided file name. A missing file is an empty
+// journal.
+func (j *Journal) Get(filename string) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+
+		return err
+	}
+
+	if len(data) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(data, j)
+}
+
+// Prints the operations oldest first with their timestamps, marking the ones
+// that were undone.
+func (j *Journal) String() string {
+	formatted := ""
+
+	for i, op := range j.Operations {
+		suffix := ""
+		if i >= j.Cursor {
+			suffix = " (undone)"
+		}
+
+		formatted += fmt.Sprintf("%d: %s %s%s\n", i+1, op.Time.Format("2006-01-02 15:04:05"), op.Name, suffix)
+	}
+
+	return formatted
+}
+
+// Returns the changes that turn the before items into the after items.
+func diffItems(before, after []TodoItem) ([]Change, error) {
+	changes := []Change{}
+
+	afterIndexes := map[int]int{}
+	for i, item := range after {
+		afterIndexes[item.ID] = i
+	}
+
+	beforeIndexes := map[int]int{}
+	for i, item := range before {
+		beforeIndexes[item.ID] = i
+
+		j, ok := afterIndexes[item.ID]
+		if !ok {
+			deleted := item
+			changes = append(changes, Change{Before: &deleted, Index: i})
+			continue
+		}
+
+		same, err := sameItem(item, after[j])
+		if err != nil {
+			return nil, err
+		}
+
+		if !same {
+			old, updated := item, after[j]
+			changes = append(changes, Change{Before: &old, After: &updated, Index: i})
+		}
+	}
+
+	for i, item := range after {
+		if _, ok := beforeIndexes[item.ID]; !ok {
+			added := item
+			changes = append(changes, Change{After: &added, Index: i})
+		}
+	}
+
+	return changes, nil
+}
+
+// Applies changes in the direction from Before to After. Items are removed and
+// replaced by ID first, then inserted in ascending order of their positions,
+// which puts every inserted item back where it was.
+func applyChanges(l *TodoList, changes []Change) error {
+	inserts := []Change{}
+
+	for _, c := range changes {
+		switch {
+		case c.Before == nil:
+			inserts = append(inserts, c)
+
+		case c.After == nil:
+			if err := l.Delete(c.Before.ID); err != nil {
+				return err
+			}
+
+		default:
+			i, err := l.indexOf(c.Before.ID)
+			if err != nil {
+				return err
+			}
+			l.Items[i] = *c.After
+		}
+	}
+
+	sort.Slice(inserts, func(a, b int) bool {
+		return inserts[a].Index < inserts[b].Index
+	})
+
+	for _, c := range inserts {
+		if _, err := l.indexOf(c.After.ID); err == nil {
+			return fmt.Errorf("%w: %d", ErrItemExists, c.After.ID)
+		}
+
+		index := c.Index
+		if index > len(l.Items) {
+			index = len(l.Items)
+		}
+
+		l.Items = append(l.Items, TodoItem{})
+		copy(l.Items[index+1:], l.Items[index:])
+		l.Items[index] = *c.After
+	}
+
+	return nil
+}
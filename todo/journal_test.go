@@ -0,0 +1,207 @@
+package todo_test
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mnishiguchi.com/todo"
+)
+
+// Lists the item IDs and their done states, such as "1 2x 3".
+func stateOf(l *todo.TodoList) string {
+	states := []string{}
+	for _, item := range l.Items {
+		state := fmt.Sprint(item.ID)
+		if item.Done {
+			state += "x"
+		}
+		states = append(states, state)
+	}
+
+	return strings.Join(states, " ")
+}
+
+func TestJournalUndoRedo(t *testing.T) {
+	l := todo.TodoList{}
+	j := todo.Journal{}
+
+	track := func(name string, change func(*todo.TodoList) error) {
+		t.Helper()
+		if err := j.Track(name, &l, change); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, task := range []string{"Task 1", "Task 2", "Task 3"} {
+		task := task
+		track("add: "+task, func(l *todo.TodoList) error {
+			l.Add(task)
+			return nil
+		})
+	}
+	track("delete: 2", func(l *todo.TodoList) error { return l.Delete(2) })
+	track("complete: 3", func(l *todo.TodoList) error { return l.Complete(3) })
+
+	testCases := []struct {
+		name     string
+		redo     bool
+		expected string
+	}{
+		{name: "UndoComplete", expected: "1 3"},
+		{name: "UndoDelete", expected: "1 2 3"},
+		{name: "UndoAdd", expected: "1 2"},
+		{name: "RedoAdd", redo: true, expected: "1 2 3"},
+		{name: "RedoDelete", redo: true, expected: "1 3"},
+		{name: "RedoComplete", redo: true, expected: "1 3x"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var err error
+			if tc.redo {
+				_, err = j.Redo(&l)
+			} else {
+				_, err = j.Undo(&l)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if state := stateOf(&l); state != tc.expected {
+				t.Errorf("Expected %q, got %q instead", tc.expected, state)
+			}
+		})
+	}
+
+	if _, err := j.Redo(&l); !errors.Is(err, todo.ErrNothingToRedo) {
+		t.Errorf("Expected %q, got %q instead", todo.ErrNothingToRedo, err)
+	}
+
+	// The next ID is restored too, so a redone add keeps its ID.
+	if l.NextID != 4 {
+		t.Errorf("Expected next ID 4, got %d instead", l.NextID)
+	}
+}
+
+func TestJournalNewOperationDiscardsRedo(t *testing.T) {
+	l := todo.TodoList{}
+	j := todo.Journal{}
+
+	add := func(l *todo.TodoList) error {
+		l.Add("Task")
+		return nil
+	}
+
+	if err := j.Track("add: Task", &l, add); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := j.Undo(&l); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Track("add: Task", &l, add); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(j.Operations) != 1 {
+		t.Errorf("Expected 1 operation, got %d instead", len(j.Operations))
+	}
+
+	// The undone item keeps its ID to itself.
+	if id := l.Items[0].ID; id != 2 {
+		t.Errorf("Expected ID 2, got %d instead", id)
+	}
+
+	if _, err := j.Redo(&l); !errors.Is(err, todo.ErrNothingToRedo) {
+		t.Errorf("Expected %q, got %q instead", todo.ErrNothingToRedo, err)
+	}
+
+	// A failed change is not recorded.
+	if err := j.Track("delete: 9", &l, func(l *todo.TodoList) error { return l.Delete(9) }); err == nil {
+		t.Errorf("Expected an error deleting a missing item")
+	}
+
+	if len(j.Operations) != 1 {
+		t.Errorf("Expected 1 operation, got %d instead", len(j.Operations))
+	}
+
+	// The undone item keeps its ID to itself.
+	if id := l.Items[0].ID; id != 2 {
+		t.Errorf("Expected ID 2, got %d instead", id)
+	}
+}
+
+func TestJournalSaveGet(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "todo.json.journal")
+
+	l := todo.TodoList{}
+	j := todo.Journal{}
+
+	if err := j.Track("add: Task", &l, func(l *todo.TodoList) error {
+		l.Add("Task")
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := j.Undo(&l); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := j.Save(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := todo.Journal{}
+	if err := loaded.Get(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasSuffix(loaded.String(), " add: Task (undone)\n") {
+		t.Errorf("Expected the undone operation in the history, got %q instead", loaded.String())
+	}
+
+	if _, err := loaded.Undo(&l); !errors.Is(err, todo.ErrNothingToUndo) {
+		t.Errorf("Expected %q, got %q instead", todo.ErrNothingToUndo, err)
+	}
+}
+
+func TestJournalForget(t *testing.T) {
+	l := todo.TodoList{}
+	j := todo.Journal{}
+
+	for _, change := range []func(*todo.TodoList) error{
+		func(l *todo.TodoList) error { l.Add("Task 1"); return nil },
+		func(l *todo.TodoList) error { l.Add("Task 2"); return nil },
+		func(l *todo.TodoList) error { return l.Complete(1) },
+		func(l *todo.TodoList) error { return l.Complete(2) },
+	} {
+		if err := j.Track("change", &l, change); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := j.Undo(&l); err != nil {
+		t.Fatal(err)
+	}
+
+	j.Forget(1)
+
+	if len(j.Operations) != 2 || j.Cursor != 1 {
+		t.Fatalf("Expected 2 operations with 1 applied, got %d with %d instead", len(j.Operations), j.Cursor)
+	}
+
+	if _, err := j.Redo(&l); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := j.Undo(&l); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := j.Undo(&l); err != nil {
+		t.Fatal(err)
+	}
+
+	if state := stateOf(&l); state != "1x" {
+		t.Errorf("Expected %q, got %q instead", "1x", state)
+	}
+}
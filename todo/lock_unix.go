@@ -0,0 +1,23 @@
+//go:build !windows
+// +build !windows
+
+package todo
+
+import (
+	"os"
+	"syscall"
+)
+
+// Blocks until an exclusive flock(2) lock is acquired.
+func lockFile(f *os.File) error {
+	for {
+		err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+		if err != syscall.EINTR {
+			return err
+		}
+	}
+}
+
+func unlockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
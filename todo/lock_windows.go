@@ -0,0 +1,44 @@
+//go:build windows
+// +build windows
+
+package todo
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// The syscall package does not expose LockFileEx, so it is loaded from
+// kernel32.dll. Windows releases the lock when the process exits, even when
+// it crashes or is killed.
+var (
+	kernel32         = syscall.NewLazyDLL("kernel32.dll")
+	procLockFileEx   = kernel32.NewProc("LockFileEx")
+	procUnlockFileEx = kernel32.NewProc("UnlockFileEx")
+)
+
+const lockfileExclusiveLock = 0x2
+
+// Blocks until an exclusive lock on the first byte of the file is acquired.
+func lockFile(f *os.File) error {
+	ol := new(syscall.Overlapped)
+
+	r, _, err := procLockFileEx.Call(f.Fd(), lockfileExclusiveLock, 0, 1, 0, uintptr(unsafe.Pointer(ol)))
+	if r == 0 {
+		return err
+	}
+
+	return nil
+}
+
+func unlockFile(f *os.File) error {
+	ol := new(syscall.Overlapped)
+
+	r, _, err := procUnlockFileEx.Call(f.Fd(), 0, 1, 0, uintptr(unsafe.Pointer(ol)))
+	if r == 0 {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package todo
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// The layout of due dates, following todo.txt.
+const DateLayout = "2006-01-02"
+
+var priorityPattern = regexp.MustCompile(`^\(([A-Z])\)\s+`)
+
+// Parses a task written in the todo.txt syntax into an item.
+//
+//	(A) Ship release +backend @office due:2026-11-01
+//
+// An optional priority comes first, "+" marks a project, "@" marks a context,
+// "due:" sets a due date and "rec:" a recurrence rule. Everything else is the
+// task description. Words that look like metadata but cannot be parsed, such
+// as "due:tomorrow", are kept in the description.
+func ParseTask(text string) TodoItem {
+	item := TodoItem{}
+
+	text = strings.TrimSpace(text)
+	if m := priorityPattern.FindStringSubmatch(text); m != nil {
+		item.Priority = m[1]
+		text = text[len(m[0]):]
+	}
+
+	words := []string{}
+	for _, word := range strings.Fields(text) {
+		switch {
+		case len(word) > 1 && strings.HasPrefix(word, "+"):
+			item.Projects = appendUnique(item.Projects, word[1:])
+		case len(word) > 1 && strings.HasPrefix(word, "@"):
+			item.Contexts = appendUnique(item.Contexts, word[1:])
+		case strings.HasPrefix(word, "due:"):
+			due, err := time.ParseInLocation(DateLayout, strings.TrimPrefix(word, "due:"), time.Local)
+			if err != nil {
+				words = append(words, word)
+				continue
+			}
+			item.Due = due
+		case strings.HasPrefix(word, "rec:"):
+			r, err := ParseRecurrence(strings.TrimPrefix(word, "rec:"))
+			if err != nil {
+				words = append(words, word)
+				continue
+			}
+			item.Recur = r.String()
+		default:
+			words = append(words, word)
+		}
+	}
+
+	item.Task = strings.Join(words, " ")
+
+	return item
+}
+
+// Formats the item in the todo.txt syntax understood by ParseTask.
+func (i TodoItem) Text() string {
+	parts := []string{}
+
+	if i.Priority != "" {
+		parts = append(parts, "("+i.Priority+")")
+	}
+
+	if i.Task != "" {
+		parts = append(parts, i.Task)
+	}
+
+	for _, project := range i.Projects {
+		parts = append(parts, "+"+project)
+	}
+
+	for _, context := range i.Contexts {
+		parts = append(parts, "@"+context)
+	}
+
+	if !i.Due.IsZero() {
+		parts = append(parts, "due:"+i.Due.Format(DateLayout))
+	}
+
+	if i.Recur != "" {
+		parts = append(parts, "rec:"+i.Recur)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+
+	return append(values, value)
+}
@@ -0,0 +1,75 @@
+package todo_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"mnishiguchi.com/todo"
+)
+
+func TestParseTask(t *testing.T) {
+	testCases := []struct {
+		name     string
+		text     string
+		expected todo.TodoItem
+	}{
+		{name: "Plain",
+			text:     "Go for a walk",
+			expected: todo.TodoItem{Task: "Go for a walk"}},
+		{name: "Full",
+			text: "(A) Ship release +backend @office due:2026-11-01",
+			expected: todo.TodoItem{
+				Task:     "Ship release",
+				Priority: "A",
+				Due:      time.Date(2026, 11, 1, 0, 0, 0, 0, time.Local),
+				Projects: []string{"backend"},
+				Contexts: []string{"office"},
+			}},
+		{name: "TagsInTheMiddle",
+			text: "Review +api docs with @bob +api",
+			expected: todo.TodoItem{
+				Task:     "Review docs with",
+				Projects: []string{"api"},
+				Contexts: []string{"bob"},
+			}},
+		{name: "PriorityNotFirst",
+			text:     "Call (B) later",
+			expected: todo.TodoItem{Task: "Call (B) later"}},
+		{name: "InvalidDueDate",
+			text:     "Pay bills due:tomorrow",
+			expected: todo.TodoItem{Task: "Pay bills due:tomorrow"}},
+		{name: "Recurrence",
+			text:     "Standup notes rec:weekday",
+			expected: todo.TodoItem{Task: "Standup notes", Recur: "weekday"}},
+		{name: "InvalidRecurrence",
+			text:     "Standup notes rec:often",
+			expected: todo.TodoItem{Task: "Standup notes rec:often"}},
+		{name: "LoneSymbols",
+			text:     "1 + 1 @ home",
+			expected: todo.TodoItem{Task: "1 + 1 @ home"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := todo.ParseTask(tc.text)
+
+			if !reflect.DeepEqual(res, tc.expected) {
+				t.Errorf("Expected %+v, got %+v instead.", tc.expected, res)
+			}
+		})
+	}
+}
+
+func TestStringShowsMetadata(t *testing.T) {
+	list := todo.TodoList{}
+	list.Add("(A) Ship release +backend due:2026-11-01")
+	list.Add("Go for a walk @home")
+
+	expected := "[ ] 1: (A) Ship release +backend due:2026-11-01\n" +
+		"[ ] 2: Go for a walk @home\n"
+
+	if res := list.String(); res != expected {
+		t.Errorf("Expected %q, got %q instead.", expected, res)
+	}
+}
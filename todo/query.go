@@ -0,0 +1,574 @@
+package todo
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Reports whether an item should be kept.
+type Filter func(item TodoItem) bool
+
+// Returns the items that match the filter, in list order. A nil filter
+// matches every item.
+func (l *TodoList) Select(filter Filter) []TodoItem {
+	items := []TodoItem{}
+
+	for _, item := range l.Items {
+		if filter == nil || filter(item) {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// Parses a filter expression such as:
+//
+//	not done and tag:backend and due<7d
+//
+// Terms are combined with "and", "or", "not" and parentheses. "and" binds
+// tighter than "or". The supported terms are:
+//
+//	done                 the item is completed
+//	tag:NAME             the item has the +project or @context NAME
+//	+NAME, @NAME         the item has the project or context NAME
+//	priority:A           the item has priority A; also <, <=, >, >=
+//	due<7d               the item is due within 7 days; also <=, >, >=, :
+//	created>2026-01-01   the item was created after the date
+//	due:none             the item has no due date
+//	WORD, "SOME WORDS"   the task contains the text, ignoring case
+//
+// A word followed by an operator must be one of the fields above; text such
+// as "http://example.com" is searched for by quoting it.
+//
+// Dates are either in the 2006-01-02 layout, "today", or relative to now, such
+// as 7d, 2w or -3d. A "<" comparison includes every day before the date, so
+// due<today matches overdue items.
+func ParseFilter(expr string, now time.Time) (Filter, error) {
+	tokens, err := tokenizeFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &filterParser{tokens: tokens, now: now}
+
+	// An empty expression matches everything.
+	if len(tokens) == 0 {
+		return func(TodoItem) bool { return true }, nil
+	}
+
+	filter, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("%w: unexpected %q", ErrInvalidFilter, p.tokens[p.pos])
+	}
+
+	return filter, nil
+}
+
+// Splits the expression into words, parentheses and quoted strings.
+func tokenizeFilter(expr string) ([]string, error) {
+	tokens := []string{}
+	word := strings.Builder{}
+
+	flush := func() {
+		if word.Len() > 0 {
+			tokens = append(tokens, word.String())
+			word.Reset()
+		}
+	}
+
+	runes := []rune(expr)
+	for i := 0; i < len(runes); i++ {
+		switch r := runes[i]; {
+		case unicode.IsSpace(r):
+			flush()
+		case r == '(' || r == ')':
+			flush()
+			tokens = append(tokens, string(r))
+		case r == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			if end == len(runes) {
+				return nil, fmt.Errorf("%w: unterminated quote", ErrInvalidFilter)
+			}
+			// Keep the quotes so that the parser treats it as text.
+			word.WriteString(string(runes[i : end+1]))
+			i = end
+		default:
+			word.WriteRune(r)
+		}
+	}
+	flush()
+
+	return tokens, nil
+}
+
+// A recursive descent parser over the filter tokens.
+type filterParser struct {
+	tokens []string
+	pos    int
+	now    time.Time
+}
+
+func (p *filterParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+
+	return ""
+}
+
+func (p *filterParser) isKeyword(keyword string) bool {
+	return strings.EqualFold(p.peek(), keyword)
+}
+
+func (p *filterParser) parseOr() (Filter, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isKeyword("or") {
+		p.pos++
+
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		l, r := left, right
+		left = func(item TodoItem) bool { return l(item) || r(item) }
+	}
+
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (Filter, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isKeyword("and") {
+		p.pos++
+
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+
+		l, r := left, right
+		left = func(item TodoItem) bool { return l(item) && r(item) }
+	}
+
+	return left, nil
+}
+
+func (p *filterParser) parseNot() (Filter, error) {
+	if p.isKeyword("not") {
+		p.pos++
+
+		f, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+
+		return func(item TodoItem) bool { return !f(item) }, nil
+	}
+
+	return p.parsePrimary()
+}
+
+func (p *filterParser) parsePrimary() (Filter, error) {
+	token := p.peek()
+
+	switch {
+	case token == "":
+		return nil, fmt.Errorf("%w: unexpected end of expression", ErrInvalidFilter)
+
+	case token == "(":
+		p.pos++
+
+		f, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("%w: missing closing parenthesis", ErrInvalidFilter)
+		}
+		p.pos++
+
+		return f, nil
+
+	case token == ")" || p.isKeyword("and") || p.isKeyword("or"):
+		return nil, fmt.Errorf("%w: unexpected %q", ErrInvalidFilter, token)
+	}
+
+	p.pos++
+
+	return p.parseTerm(token)
+}
+
+// The comparison operators, longest first so that "<=" is not read as "<".
+var filterOperators = []string{"<=", ">=", "<", ">", ":", "="}
+
+func (p *filterParser) parseTerm(token string) (Filter, error) {
+	switch {
+	case strings.HasPrefix(token, `"`):
+		return textFilter(strings.Trim(token, `"`)), nil
+
+	case strings.EqualFold(token, "done"):
+		return func(item TodoItem) bool { return item.Done }, nil
+
+	case len(token) > 1 && strings.HasPrefix(token, "+"):
+		return projectFilter(token[1:]), nil
+
+	case len(token) > 1 && strings.HasPrefix(token, "@"):
+		return contextFilter(token[1:]), nil
+	}
+
+	for _, op := range filterOperators {
+		i := strings.Index(token, op)
+		if i <= 0 {
+			continue
+		}
+
+		field, value := strings.ToLower(token[:i]), token[i+len(op):]
+
+		isEqual := op == ":" || op == "="
+
+		switch field {
+		case "tag":
+			if !isEqual {
+				break
+			}
+			project, context := projectFilter(value), contextFilter(value)
+			return func(item TodoItem) bool { return project(item) || context(item) }, nil
+
+		case "project":
+			if !isEqual {
+				break
+			}
+			return projectFilter(value), nil
+
+		case "context":
+			if !isEqual {
+				break
+			}
+			return contextFilter(value), nil
+
+		case "priority":
+			return priorityFilter(op, strings.ToUpper(value))
+
+		case "due", "created", "completed":
+			return p.dateFilter(field, op, value)
+
+		default:
+			// A word before the operator is most likely a misspelled field,
+			// such as prio:A. Anything else, such as "10:30", is text.
+			if isWord(field) {
+				return nil, fmt.Errorf("%w: unknown field %q", ErrInvalidFilter, field)
+			}
+			return textFilter(token), nil
+		}
+
+		return nil, fmt.Errorf("%w: %q does not support %q", ErrInvalidFilter, field, op)
+	}
+
+	return textFilter(token), nil
+}
+
+// Reports whether the string is made only of letters.
+func isWord(s string) bool {
+	for _, r := range s {
+		if !unicode.IsLetter(r) {
+			return false
+		}
+	}
+
+	return s != ""
+}
+
+func textFilter(text string) Filter {
+	text = strings.ToLower(text)
+
+	return func(item TodoItem) bool {
+		return strings.Contains(strings.ToLower(item.Task), text)
+	}
+}
+
+func projectFilter(name string) Filter {
+	return func(item TodoItem) bool { return containsFold(item.Projects, name) }
+}
+
+func contextFilter(name string) Filter {
+	return func(item TodoItem) bool { return containsFold(item.Contexts, name) }
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Compares priorities where "A" is the highest, so "priority<C" matches A and
+// B. Items without a priority never match a comparison.
+func priorityFilter(op, value string) (Filter, error) {
+	if value == "NONE" {
+		if op != ":" && op != "=" {
+			return nil, fmt.Errorf("%w: priority:none does not support %q", ErrInvalidFilter, op)
+		}
+		return func(item TodoItem) bool { return item.Priority == "" }, nil
+	}
+
+	if len(value) != 1 || value[0] < 'A' || value[0] > 'Z' {
+		return nil, fmt.Errorf("%w: invalid priority %q", ErrInvalidFilter, value)
+	}
+
+	return func(item TodoItem) bool {
+		if item.Priority == "" {
+			return false
+		}
+
+		return compareOp(op, strings.Compare(item.Priority, value))
+	}, nil
+}
+
+// Compares the day of a date field with a date value.
+func (p *filterParser) dateFilter(field, op, value string) (Filter, error) {
+	get := func(item TodoItem) time.Time {
+		switch field {
+		case "due":
+			return item.Due
+		case "created":
+			return item.CreatedAt
+		}
+		return item.CompletedAt
+	}
+
+	if strings.EqualFold(value, "none") {
+		if op != ":" && op != "=" {
+			return nil, fmt.Errorf("%w: %s:none does not support %q", ErrInvalidFilter, field, op)
+		}
+		return func(item TodoItem) bool { return get(item).IsZero() }, nil
+	}
+
+	date, err := parseFilterDate(value, p.now)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(item TodoItem) bool {
+		t := get(item)
+		if t.IsZero() {
+			return false
+		}
+
+		return compareOp(op, compareDays(t, date))
+	}, nil
+}
+
+// Parses an absolute date, "today", or a date relative to now such as 7d.
+func parseFilterDate(value string, now time.Time) (time.Time, error) {
+	if strings.EqualFold(value, "today") {
+		return now, nil
+	}
+
+	if t, err := time.ParseInLocation(DateLayout, value, now.Location()); err == nil {
+		return t, nil
+	}
+
+	d, err := ParseDays(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: invalid date %q", ErrInvalidFilter, value)
+	}
+
+	return now.AddDate(0, 0, d), nil
+}
+
+// Parses a number of days such as 7d, 2w, or -3d.
+func ParseDays(value string) (int, error) {
+	if len(value) < 2 {
+		return 0, fmt.Errorf("invalid number of days %q", value)
+	}
+
+	n, err := strconv.Atoi(value[:len(value)-1])
+	if err != nil {
+		return 0, fmt.Errorf("invalid number of days %q", value)
+	}
+
+	switch value[len(value)-1] {
+	case 'd':
+		return n, nil
+	case 'w':
+		return n * 7, nil
+	}
+
+	return 0, fmt.Errorf("invalid number of days %q", value)
+}
+
+// Compares the calendar days of two times in the location of b: -1, 0 or 1.
+func compareDays(a, b time.Time) int {
+	da, db := startOfDay(a.In(b.Location())), startOfDay(b)
+
+	switch {
+	case da.Before(db):
+		return -1
+	case da.After(db):
+		return 1
+	}
+	return 0
+}
+
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// Applies a comparison operator to the result of a three-way comparison.
+func compareOp(op string, cmp int) bool {
+	switch op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+
+	return cmp == 0
+}
+
+// Compares two items; it reports whether a sorts before b.
+type SortFunc func(a, b TodoItem) bool
+
+// Parses a comma-separated list of sort keys such as "priority,due". A key
+// prefixed with "-" sorts in descending order. Items without a priority or a
+// due date always sort last. The keys are id, task, done, priority, due,
+// created and completed.
+func ParseSort(spec string) (SortFunc, error) {
+	compares := []func(a, b TodoItem) int{}
+
+	for _, key := range strings.Split(spec, ",") {
+		key = strings.TrimSpace(strings.ToLower(key))
+		if key == "" {
+			continue
+		}
+
+		descending := strings.HasPrefix(key, "-")
+		key = strings.TrimPrefix(key, "-")
+
+		cmp, err := sortCompare(key, descending)
+		if err != nil {
+			return nil, err
+		}
+
+		compares = append(compares, cmp)
+	}
+
+	return func(a, b TodoItem) bool {
+		for _, cmp := range compares {
+			if c := cmp(a, b); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	}, nil
+}
+
+// Returns the comparison for the sort key. Descending order reverses the
+// comparison of the values but not the place of missing values.
+func sortCompare(key string, descending bool) (func(a, b TodoItem) int, error) {
+	dir := 1
+	if descending {
+		dir = -1
+	}
+
+	switch key {
+	case "id":
+		return func(a, b TodoItem) int { return dir * compareInts(a.ID, b.ID) }, nil
+	case "task":
+		return func(a, b TodoItem) int {
+			return dir * strings.Compare(strings.ToLower(a.Task), strings.ToLower(b.Task))
+		}, nil
+	case "done":
+		return func(a, b TodoItem) int { return dir * compareBools(a.Done, b.Done) }, nil
+	case "priority":
+		return func(a, b TodoItem) int {
+			// Missing priorities sort last.
+			if a.Priority == "" || b.Priority == "" {
+				return compareBools(a.Priority == "", b.Priority == "")
+			}
+			return dir * strings.Compare(a.Priority, b.Priority)
+		}, nil
+	case "due":
+		return timeCompare(func(i TodoItem) time.Time { return i.Due }, dir), nil
+	case "created":
+		return timeCompare(func(i TodoItem) time.Time { return i.CreatedAt }, dir), nil
+	case "completed":
+		return timeCompare(func(i TodoItem) time.Time { return i.CompletedAt }, dir), nil
+	}
+
+	return nil, fmt.Errorf("%w: %q", ErrInvalidSortKey, key)
+}
+
+// Compares times in the direction, 1 or -1, where the zero time sorts last.
+func timeCompare(get func(TodoItem) time.Time, dir int) func(a, b TodoItem) int {
+	return func(a, b TodoItem) int {
+		ta, tb := get(a), get(b)
+		if ta.IsZero() || tb.IsZero() {
+			return compareBools(ta.IsZero(), tb.IsZero())
+		}
+
+		switch {
+		case ta.Before(tb):
+			return -dir
+		case ta.After(tb):
+			return dir
+		}
+		return 0
+	}
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// Sorts false before true.
+func compareBools(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case !a:
+		return -1
+	}
+	return 1
+}
+
+// Sorts the items in place, keeping the list order of equal items.
+func SortItems(items []TodoItem, less SortFunc) {
+	sort.SliceStable(items, func(i, j int) bool { return less(items[i], items[j]) })
+}
@@ -0,0 +1,145 @@
+package todo_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"mnishiguchi.com/todo"
+)
+
+// A fixed list used by the query tests.
+func queryFixture(now time.Time) todo.TodoList {
+	day := func(n int) time.Time { return now.AddDate(0, 0, n) }
+
+	return todo.TodoList{Items: []todo.TodoItem{
+		{ID: 1, Task: "Ship release", Priority: "A", Due: day(3), Projects: []string{"backend"}, CreatedAt: day(-10)},
+		{ID: 2, Task: "Write docs", Priority: "C", Due: day(10), Projects: []string{"docs"}, CreatedAt: day(-5)},
+		{ID: 3, Task: "Fix login bug", Priority: "B", Due: day(-1), Projects: []string{"backend"}, Contexts: []string{"office"}, CreatedAt: day(-3)},
+		{ID: 4, Task: "Buy milk", Contexts: []string{"store"}, CreatedAt: day(-1)},
+		{ID: 5, Task: "Deploy backend", Done: true, Priority: "A", Projects: []string{"backend"}, CreatedAt: day(-20), CompletedAt: day(-2)},
+	}}
+}
+
+func TestParseFilter(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	list := queryFixture(now)
+
+	testCases := []struct {
+		name        string
+		expr        string
+		expectedIDs []int
+		expectedErr error
+	}{
+		{name: "Empty", expr: "", expectedIDs: []int{1, 2, 3, 4, 5}},
+		{name: "Done", expr: "done", expectedIDs: []int{5}},
+		{name: "NotDone", expr: "not done", expectedIDs: []int{1, 2, 3, 4}},
+		{name: "Tag", expr: "tag:backend", expectedIDs: []int{1, 3, 5}},
+		{name: "TagContext", expr: "tag:office", expectedIDs: []int{3}},
+		{name: "ProjectShorthand", expr: "+docs", expectedIDs: []int{2}},
+		{name: "ContextShorthand", expr: "@store", expectedIDs: []int{4}},
+		{name: "DueWithinDays", expr: "due<7d", expectedIDs: []int{1, 3}},
+		{name: "Overdue", expr: "due<today", expectedIDs: []int{3}},
+		{name: "DueAbsolute", expr: "due>=2026-10-26", expectedIDs: []int{2}},
+		{name: "DueNone", expr: "due:none", expectedIDs: []int{4, 5}},
+		{name: "PriorityEqual", expr: "priority:a", expectedIDs: []int{1, 5}},
+		{name: "PriorityHigher", expr: "priority<C", expectedIDs: []int{1, 3, 5}},
+		{name: "PriorityNone", expr: "priority:none", expectedIDs: []int{4}},
+		{name: "Created", expr: "created>-4d", expectedIDs: []int{3, 4}},
+		{name: "Completed", expr: "completed>=-2d", expectedIDs: []int{5}},
+		{name: "Text", expr: "DOCS", expectedIDs: []int{2}},
+		{name: "QuotedText", expr: `"login bug"`, expectedIDs: []int{3}},
+		{name: "Combined", expr: "not done and tag:backend and due<7d", expectedIDs: []int{1, 3}},
+		{name: "OrBindsLooser", expr: "done or tag:docs and priority:C", expectedIDs: []int{2, 5}},
+		{name: "Parentheses", expr: "(done or tag:docs) and priority:A", expectedIDs: []int{5}},
+		{name: "NestedNot", expr: "not not done", expectedIDs: []int{5}},
+		{name: "KeywordsIgnoreCase", expr: "NOT done AND @store", expectedIDs: []int{4}},
+		{name: "MissingOperand", expr: "done and", expectedErr: todo.ErrInvalidFilter},
+		{name: "LeadingOperator", expr: "or done", expectedErr: todo.ErrInvalidFilter},
+		{name: "UnbalancedOpen", expr: "(done", expectedErr: todo.ErrInvalidFilter},
+		{name: "UnbalancedClose", expr: "done)", expectedErr: todo.ErrInvalidFilter},
+		{name: "UnterminatedQuote", expr: `"login`, expectedErr: todo.ErrInvalidFilter},
+		{name: "InvalidDate", expr: "due<soon", expectedErr: todo.ErrInvalidFilter},
+		{name: "InvalidPriority", expr: "priority:AB", expectedErr: todo.ErrInvalidFilter},
+		{name: "InvalidTagOperator", expr: "tag<backend", expectedErr: todo.ErrInvalidFilter},
+		{name: "UnknownField", expr: "prio:high", expectedErr: todo.ErrInvalidFilter},
+		{name: "QuotedUnknownField", expr: `"prio:high"`, expectedIDs: []int{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			filter, err := todo.ParseFilter(tc.expr, now)
+
+			if tc.expectedErr != nil {
+				if !errors.Is(err, tc.expectedErr) {
+					t.Errorf("Expected error %q, got %v instead.", tc.expectedErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			res := []int{}
+			for _, item := range list.Select(filter) {
+				res = append(res, item.ID)
+			}
+
+			if !reflect.DeepEqual(res, tc.expectedIDs) {
+				t.Errorf("Expected IDs %v, got %v instead.", tc.expectedIDs, res)
+			}
+		})
+	}
+}
+
+func TestParseSort(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	list := queryFixture(now)
+
+	testCases := []struct {
+		name        string
+		spec        string
+		expectedIDs []int
+		expectedErr error
+	}{
+		{name: "Priority", spec: "priority", expectedIDs: []int{1, 5, 3, 2, 4}},
+		{name: "PriorityDue", spec: "priority,due", expectedIDs: []int{1, 5, 3, 2, 4}},
+		{name: "Due", spec: "due", expectedIDs: []int{3, 1, 2, 4, 5}},
+		{name: "DescendingID", spec: "-id", expectedIDs: []int{5, 4, 3, 2, 1}},
+		{name: "DescendingPriority", spec: "-priority", expectedIDs: []int{2, 3, 1, 5, 4}},
+		{name: "DescendingDue", spec: "-due", expectedIDs: []int{2, 1, 3, 4, 5}},
+		{name: "DoneThenTask", spec: "done, task", expectedIDs: []int{4, 3, 1, 2, 5}},
+		{name: "InvalidKey", spec: "size", expectedErr: todo.ErrInvalidSortKey},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			less, err := todo.ParseSort(tc.spec)
+
+			if tc.expectedErr != nil {
+				if !errors.Is(err, tc.expectedErr) {
+					t.Errorf("Expected error %q, got %v instead.", tc.expectedErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			items := list.Select(nil)
+			todo.SortItems(items, less)
+
+			res := []int{}
+			for _, item := range items {
+				res = append(res, item.ID)
+			}
+
+			if !reflect.DeepEqual(res, tc.expectedIDs) {
+				t.Errorf("Expected IDs %v, got %v instead.", tc.expectedIDs, res)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package todo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A rule that makes an item repeat, written "rec:" followed by a rule in the
+// todo.txt syntax:
+//
+//	rec:30d     30 days after the item is completed
+//	rec:2w      2 weeks after the item is completed
+//	rec:1m      1 month after the item is completed
+//	rec:1y      1 year after the item is completed
+//	rec:weekday the next Monday to Friday after the item is completed
+//
+// A leading "+", as in "rec:+1m", counts from the due date instead of the
+// completion date, so that a monthly bill stays on the same day of the month
+// even when it is paid late.
+type Recurrence struct {
+	N       int  // the number of units between occurrences
+	Unit    byte // 'd', 'w', 'm' or 'y'; zero for weekdays
+	Weekday bool // repeats on the next weekday
+	Strict  bool // counts from the due date rather than the completion date
+}
+
+// Parses a recurrence rule such as "30d", "+1m" or "weekday".
+func ParseRecurrence(rule string) (Recurrence, error) {
+	r := Recurrence{}
+
+	value := rule
+	if strings.HasPrefix(value, "+") {
+		r.Strict = true
+		value = value[1:]
+	}
+
+	if value == "weekday" {
+		r.Weekday = true
+		return r, nil
+	}
+
+	if len(value) < 2 {
+		return Recurrence{}, fmt.Errorf("%w: %q", ErrInvalidRecurrence, rule)
+	}
+
+	n, err := strconv.Atoi(value[:len(value)-1])
+	if err != nil || n < 1 {
+		return Recurrence{}, fmt.Errorf("%w: %q", ErrInvalidRecurrence, rule)
+	}
+
+	switch unit := value[len(value)-1]; unit {
+	case 'd', 'w', 'm', 'y':
+		r.N = n
+		r.Unit = unit
+	default:
+		return Recurrence{}, fmt.Errorf("%w: %q", ErrInvalidRecurrence, rule)
+	}
+
+	return r, nil
+}
+
+// Formats the rule in the syntax understood by ParseRecurrence.
+func (r Recurrence) String() string {
+	prefix := ""
+	if r.Strict {
+		prefix = "+"
+	}
+
+	if r.Weekday {
+		return prefix + "weekday"
+	}
+
+	return fmt.Sprintf("%s%d%c", prefix, r.N, r.Unit)
+}
+
+// Returns the day of the occurrence after the one that is due on due and was
+// completed at completed. Months and years are added with time.AddDate, so
+// the 31st of a month may move to the beginning of the month after next.
+func (r Recurrence) Next(due, completed time.Time) time.Time {
+	from := startOfDay(completed)
+	if r.Strict && !due.IsZero() {
+		from = startOfDay(due)
+	}
+
+	if r.Weekday {
+		next := from.AddDate(0, 0, 1)
+		for next.Weekday() == time.Saturday || next.Weekday() == time.Sunday {
+			next = next.AddDate(0, 0, 1)
+		}
+		return next
+	}
+
+	switch r.Unit {
+	case 'w':
+		return from.AddDate(0, 0, 7*r.N)
+	case 'm':
+		return from.AddDate(0, r.N, 0)
+	case 'y':
+		return from.AddDate(r.N, 0, 0)
+	}
+
+	return from.AddDate(0, 0, r.N)
+}
+
+// Returns the items of the series that the item with the given ID belongs to,
+// oldest first: the completed occurrences followed by the open one.
+func (l *TodoList) Series(id int) ([]TodoItem, error) {
+	item, err := l.Find(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if item.SeriesID == 0 {
+		return []TodoItem{item}, nil
+	}
+
+	return l.Select(func(i TodoItem) bool {
+		return i.SeriesID == item.SeriesID
+	}), nil
+}
+
+// Reports whether the series of the item already has an open occurrence
+// after it, as when the item was completed, marked as not completed and
+// completed again.
+func (l *TodoList) hasOpenSuccessor(index int) bool {
+	item := l.Items[index]
+	if item.SeriesID == 0 {
+		return false
+	}
+
+	for _, other := range l.Items {
+		if other.ID != item.ID && other.SeriesID == item.SeriesID && !other.Done && !other.CreatedAt.Before(item.CreatedAt) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Adds the occurrence that follows the item, which has just been completed.
+func (l *TodoList) addNextOccurrence(index int) {
+	item := &l.Items[index]
+
+	r, err := ParseRecurrence(item.Recur)
+	if err != nil {
+		return
+	}
+
+	if item.SeriesID == 0 {
+		item.SeriesID = item.ID
+	}
+
+	next := *item
+	next.ID = l.nextID()
+	next.UID = newUID()
+	next.Modified = nil
+	next.Sessions = nil
+	next.Done = false
+	next.CreatedAt = item.CompletedAt
+	next.CompletedAt = time.Time{}
+	next.Due = r.Next(item.Due, item.CompletedAt)
+	next.Projects = append([]string(nil), item.Projects...)
+	next.Contexts = append([]string(nil), item.Contexts...)
+	next.BlockedBy = append([]int(nil), item.BlockedBy...)
+
+	l.Items = append(l.Items, next)
+}
@@ -0,0 +1,118 @@
+package todo_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"mnishiguchi.com/todo"
+)
+
+func TestRecurrenceNext(t *testing.T) {
+	// A Friday
+	completed := time.Date(2026, 10, 16, 18, 30, 0, 0, time.Local)
+	due := time.Date(2026, 10, 10, 0, 0, 0, 0, time.Local)
+
+	testCases := []struct {
+		name     string
+		rule     string
+		due      time.Time
+		expected time.Time
+		err      error
+	}{
+		{name: "Days", rule: "30d", due: due, expected: time.Date(2026, 11, 15, 0, 0, 0, 0, time.Local)},
+		{name: "Weeks", rule: "2w", expected: time.Date(2026, 10, 30, 0, 0, 0, 0, time.Local)},
+		{name: "Months", rule: "1m", expected: time.Date(2026, 11, 16, 0, 0, 0, 0, time.Local)},
+		{name: "Years", rule: "1y", expected: time.Date(2027, 10, 16, 0, 0, 0, 0, time.Local)},
+		{name: "WeekdaySkipsWeekend", rule: "weekday", expected: time.Date(2026, 10, 19, 0, 0, 0, 0, time.Local)},
+		{name: "StrictFromDue", rule: "+1m", due: due, expected: time.Date(2026, 11, 10, 0, 0, 0, 0, time.Local)},
+		{name: "StrictWithoutDue", rule: "+1w", expected: time.Date(2026, 10, 23, 0, 0, 0, 0, time.Local)},
+		{name: "StrictWeekday", rule: "+weekday", due: due, expected: time.Date(2026, 10, 12, 0, 0, 0, 0, time.Local)},
+		{name: "InvalidUnit", rule: "3h", err: todo.ErrInvalidRecurrence},
+		{name: "InvalidNumber", rule: "0d", err: todo.ErrInvalidRecurrence},
+		{name: "Empty", rule: "", err: todo.ErrInvalidRecurrence},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := todo.ParseRecurrence(tc.rule)
+			if tc.err != nil {
+				if !errors.Is(err, tc.err) {
+					t.Fatalf("Expected error %q, got %q instead", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if r.String() != tc.rule {
+				t.Errorf("Expected %q, got %q instead", tc.rule, r.String())
+			}
+
+			if res := r.Next(tc.due, completed); !res.Equal(tc.expected) {
+				t.Errorf("Expected %s, got %s instead", tc.expected, res)
+			}
+		})
+	}
+}
+
+func TestCompleteRecurring(t *testing.T) {
+	list := todo.TodoList{}
+	list.Add("Rotate certs +ops due:2026-10-01 rec:+30d")
+	list.Add("Water plants")
+
+	if err := list.Complete(1); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(list.Items) != 3 {
+		t.Fatalf("Expected the next occurrence to be added, got %d items instead", len(list.Items))
+	}
+
+	next := list.Items[2]
+	expectedDue := time.Date(2026, 10, 31, 0, 0, 0, 0, time.Local)
+
+	if next.ID != 3 || next.Done || !next.Due.Equal(expectedDue) {
+		t.Errorf("Expected an open item 3 due on %s, got %+v instead", expectedDue, next)
+	}
+
+	if next.Text() != "Rotate certs +ops due:2026-10-31 rec:+30d" {
+		t.Errorf("Expected the same task, got %q instead", next.Text())
+	}
+
+	// Completing the same item again does not add another occurrence.
+	if err := list.Complete(1); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nor does completing it after marking it as not completed, since the
+	// next occurrence is still open.
+	if err := list.Uncomplete(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := list.Complete(1); err != nil {
+		t.Fatal(err)
+	}
+
+	if open := list.Select(func(i todo.TodoItem) bool { return !i.Done }); len(list.Items) != 3 || len(open) != 2 {
+		t.Fatalf("Expected 3 items with 2 open, got %+v instead", list.Items)
+	}
+
+	if err := list.Complete(3); err != nil {
+		t.Fatal(err)
+	}
+
+	series, err := list.Series(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(series) != 3 || !series[0].Done || !series[1].Done || series[2].Done {
+		t.Errorf("Expected 2 completed occurrences and an open one, got %+v instead", series)
+	}
+
+	if plain, _ := list.Series(2); len(plain) != 1 {
+		t.Errorf("Expected a single item for a non-recurring item, got %+v instead", plain)
+	}
+}
@@ -0,0 +1,90 @@
+package todo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// The path of the sync endpoint of todo_server.
+const SyncPath = "/sync"
+
+// The state of a copy of a list, as exchanged when syncing.
+type SyncState struct {
+	Items   []TodoItem
+	Deleted []Tombstone
+}
+
+// The reply of the server to a sync: its state after merging the state it
+// received, and what the merge changed on the server.
+type SyncReply struct {
+	State  SyncState
+	Report MergeReport
+}
+
+// A todo_server that lists are synced with, such as "http://host:8080".
+type Remote struct {
+	URL    string
+	Client *http.Client // http.DefaultClient when nil
+}
+
+// Returns the state of the list to send when syncing. Archived items are not
+// deleted from the other copies, though their tombstones keep the copies from
+// bringing them back to this one.
+func (l *TodoList) SyncState() SyncState {
+	state := SyncState{Items: l.Items}
+	for _, t := range l.Deleted {
+		if !t.Archived {
+			state.Deleted = append(state.Deleted, t)
+		}
+	}
+
+	return state
+}
+
+// Syncs the list with the server in a single round trip: the server merges the
+// list into its own and replies with the result, which is merged back into
+// the list. Returns what changed on the server and what changed in the list.
+func (r *Remote) Sync(l *TodoList) (pushed, pulled MergeReport, err error) {
+	body, err := json.Marshal(l.SyncState())
+	if err != nil {
+		return pushed, pulled, err
+	}
+
+	client := r.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	url := strings.TrimSuffix(r.URL, "/") + SyncPath
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return pushed, pulled, fmt.Errorf("%w: %v", ErrSync, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return pushed, pulled, fmt.Errorf("%w: %s: %s", ErrSync, resp.Status, bytes.TrimSpace(msg))
+	}
+
+	reply := SyncReply{}
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return pushed, pulled, fmt.Errorf("%w: %v", ErrSync, err)
+	}
+
+	pulled = l.Merge(reply.State.Items, reply.State.Deleted)
+
+	// The tombstones of archived items only keep the copy of the server from
+	// coming back, so they are dropped once the server no longer has it.
+	onServer := map[string]bool{}
+	for _, item := range reply.State.Items {
+		onServer[item.UID] = true
+	}
+	l.filterTombstones(func(t Tombstone) bool { return !t.Archived || onServer[t.UID] })
+
+	return reply.Report, pulled, nil
+}
@@ -0,0 +1,226 @@
+package todo
+
+import (
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// How much recency adds to the relevance of a match, which is at most about
+// 1. A match modified now gets the whole weight, one modified 30 days ago half
+// of it, so recency mostly decides between matches of similar relevance.
+const searchRecencyWeight = 0.1
+
+// Tags describe an item in a word, so a match on a tag counts more than a
+// match on a word of the task.
+const searchTagWeight = 1.25
+
+// An item found by Search and how well it matches.
+type SearchResult struct {
+	Item  TodoItem
+	Score float64
+}
+
+// A word of an item that search terms are matched against.
+type searchWord struct {
+	text  string
+	isTag bool
+}
+
+// Returns the items matching every term of the query, best matches first.
+//
+// The query and the task text and tags of the items are split into words,
+// ignoring case and punctuation. A term matches a word that is the same,
+// starts with the term, contains it, or differs from it by a typo or two;
+// exact matches count the most. The relevance of an item is the average of
+// the best match of each term, and more recently modified items rank higher.
+func (l *TodoList) Search(query string, now time.Time) []SearchResult {
+	terms := searchTokens(query)
+	results := []SearchResult{}
+
+	if len(terms) == 0 {
+		return results
+	}
+
+	for _, item := range l.Items {
+		relevance, ok := searchRelevance(terms, searchWords(item))
+		if !ok {
+			continue
+		}
+
+		age := now.Sub(item.lastModified()).Hours() / 24
+		if age < 0 {
+			age = 0
+		}
+		recency := 1 / (1 + age/30)
+
+		results = append(results, SearchResult{
+			Item:  item,
+			Score: relevance + searchRecencyWeight*recency,
+		})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+
+	return results
+}
+
+// Returns the average of the best match of each term among the words, and
+// false when a term matches none of them.
+func searchRelevance(terms []string, words []searchWord) (float64, bool) {
+	total := 0.0
+
+	for _, term := range terms {
+		best := 0.0
+		for _, word := range words {
+			score := matchScore(term, word.text)
+			if word.isTag {
+				score *= searchTagWeight
+			}
+			if score > best {
+				best = score
+			}
+		}
+
+		if best == 0 {
+			return 0, false
+		}
+		total += best
+	}
+
+	return total / float64(len(terms)), true
+}
+
+// Returns how well a term matches a word, from 0 for no match to 1 for the
+// same word.
+func matchScore(term, word string) float64 {
+	if term == word {
+		return 1
+	}
+
+	score := 0.0
+	termLen := len([]rune(term))
+
+	if termLen >= 2 && strings.HasPrefix(word, term) {
+		score = 0.8
+	}
+
+	if termLen >= 3 && score < 0.5 && strings.Contains(word, term) {
+		score = 0.5
+	}
+
+	limit := typoLimit(termLen)
+	if d := editDistance(term, word, limit); d <= limit {
+		fuzzy := 0.6 - 0.2*float64(d-1)
+		if fuzzy > score {
+			score = fuzzy
+		}
+	}
+
+	return score
+}
+
+// Returns the number of typos tolerated in a term of the length. Short terms
+// must match exactly, or they would match almost anything.
+func typoLimit(length int) int {
+	switch {
+	case length <= 3:
+		return 0
+	case length <= 6:
+		return 1
+	}
+
+	return 2
+}
+
+// Returns the edit distance between two words, counting an insertion, a
+// deletion, a substitution or a swap of adjacent letters as one edit, or
+// limit+1 when it is more than limit.
+func editDistance(a, b string, limit int) int {
+	ra, rb := []rune(a), []rune(b)
+
+	if diff := len(ra) - len(rb); diff > limit || -diff > limit {
+		return limit + 1
+	}
+
+	// Only the last three rows of the distance matrix are needed.
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	prevMin := 0
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+
+			curr[j] = minInt(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				curr[j] = minInt(curr[j], prev2[j-2]+1)
+			}
+
+			if curr[j] < rowMin {
+				rowMin = curr[j]
+			}
+		}
+
+		// A swap reaches back two rows, so stop only when both exceed the limit.
+		if rowMin > limit && prevMin > limit {
+			return limit + 1
+		}
+		prevMin = rowMin
+		prev2, prev, curr = prev, curr, prev2
+	}
+
+	if prev[len(rb)] > limit {
+		return limit + 1
+	}
+
+	return prev[len(rb)]
+}
+
+func minInt(values ...int) int {
+	min := values[0]
+	for _, v := range values[1:] {
+		if v < min {
+			min = v
+		}
+	}
+
+	return min
+}
+
+// Returns the words of the task text and tags of the item.
+func searchWords(item TodoItem) []searchWord {
+	words := []searchWord{}
+
+	for _, token := range searchTokens(item.Task) {
+		words = append(words, searchWord{text: token})
+	}
+
+	for _, tag := range append(append([]string(nil), item.Projects...), item.Contexts...) {
+		for _, token := range searchTokens(tag) {
+			words = append(words, searchWord{text: token, isTag: true})
+		}
+	}
+
+	return words
+}
+
+// Splits the text into lowercase words of letters and digits.
+func searchTokens(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
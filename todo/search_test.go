@@ -0,0 +1,54 @@
+package todo_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"mnishiguchi.com/todo"
+)
+
+func TestSearch(t *testing.T) {
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	created := func(daysAgo int) time.Time { return now.AddDate(0, 0, -daysAgo) }
+
+	list := todo.TodoList{Items: []todo.TodoItem{
+		{ID: 1, Task: "Ship the release", Projects: []string{"backend"}, CreatedAt: created(40)},
+		{ID: 2, Task: "Write release notes", Contexts: []string{"office"}, CreatedAt: created(2)},
+		{ID: 3, Task: "Review the releaser script", CreatedAt: created(1)},
+		{ID: 4, Task: "Fix login bug", Projects: []string{"backend"}, CreatedAt: created(5)},
+		{ID: 5, Task: "Pay bills", CreatedAt: created(0)},
+	}}
+
+	testCases := []struct {
+		name     string
+		query    string
+		expected []int
+	}{
+		{name: "RankedByRecency", query: "release", expected: []int{2, 1, 3}},
+		{name: "IgnoresCase", query: "RELEASE Notes", expected: []int{2}},
+		{name: "EveryTerm", query: "release backend", expected: []int{1}},
+		{name: "Tag", query: "+backend", expected: []int{4, 1}},
+		{name: "TagRanksFirst", query: "office", expected: []int{2}},
+		{name: "Prefix", query: "rev", expected: []int{3}},
+		{name: "Typo", query: "relaese notse", expected: []int{2}},
+		{name: "ShortTermsExact", query: "bg", expected: []int{}},
+		{name: "PrefixTypo", query: "bils", expected: []int{5}},
+		{name: "ExactBeforeFuzzy", query: "releaser", expected: []int{3, 2, 1}},
+		{name: "NoMatch", query: "groceries", expected: []int{}},
+		{name: "Empty", query: " ,", expected: []int{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ids := []int{}
+			for _, result := range list.Search(tc.query, now) {
+				ids = append(ids, result.Item.ID)
+			}
+
+			if !reflect.DeepEqual(ids, tc.expected) {
+				t.Errorf("Expected %v, got %v instead", tc.expected, ids)
+			}
+		})
+	}
+}
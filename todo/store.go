@@ -0,0 +1,63 @@
+package todo
+
+import "fmt"
+
+// Loads and saves a list. A store remembers what it loaded, so a Save after a
+// Load only has to write the changes when the backend supports it.
+type Store interface {
+	Load(l *TodoList) error
+	Save(l *TodoList) error
+}
+
+// A store whose file keeps more than the list, such as the history of its
+// changes, and can be rewritten to keep only the list.
+type Compacter interface {
+	Compact(l *TodoList) error
+}
+
+// The names of the available store kinds.
+const (
+	StoreJSON     = "json"
+	StoreEventLog = "eventlog"
+	StoreKV       = "kv"
+)
+
+// Returns a new store of the given kind backed by the provided file name.
+func NewStore(kind, filename string) (Store, error) {
+	switch kind {
+	case StoreJSON, "":
+		return &JSONFileStore{Filename: filename}, nil
+	case StoreEventLog:
+		return &EventLogStore{Filename: filename}, nil
+	case StoreKV:
+		return &KVStore{Filename: filename}, nil
+	}
+
+	return nil, fmt.Errorf("%w: %q", ErrInvalidStore, kind)
+}
+
+// Returns the default file name for a store kind.
+func DefaultFilename(kind string) string {
+	switch kind {
+	case StoreEventLog:
+		return ".todo.events"
+	case StoreKV:
+		return ".todo.kv"
+	}
+
+	return ".todo.json"
+}
+
+// Stores the whole list as a single JSON document. This is the original file
+// format used by TodoList.Get and TodoList.Save.
+type JSONFileStore struct {
+	Filename string
+}
+
+func (s *JSONFileStore) Load(l *TodoList) error {
+	return l.Get(s.Filename)
+}
+
+func (s *JSONFileStore) Save(l *TodoList) error {
+	return l.Save(s.Filename)
+}
@@ -0,0 +1,346 @@
+package todo
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Stores the list as an append-only log of events, one JSON object per line.
+// Saving appends an event for every item that was added, changed or deleted
+// since the list was loaded, and the order of the items when it changed, so the
+// file keeps the full history of the list.
+// Each save ends with a commit event, and events without one are ignored, so
+// a save cut short by a crash is not applied halfway.
+type EventLogStore struct {
+	Filename string
+
+	loaded    *TodoList // the state after the last Load or Save
+	validSize int64     // size of the file up to the last commit event
+}
+
+// A single change to the list.
+type event struct {
+	Op        string     // "put", "delete", "order", "next_id", "tombstone" or "commit"
+	Item      *TodoItem  `json:",omitempty"`
+	ID        int        `json:",omitempty"`
+	Order     []int      `json:",omitempty"`
+	NextID    int        `json:",omitempty"`
+	Tombstone *Tombstone `json:",omitempty"`
+}
+
+func (s *EventLogStore) Load(l *TodoList) error {
+	replayed, err := s.replay()
+	if err != nil {
+		return err
+	}
+
+	loaded, err := copyList(replayed)
+	if err != nil {
+		return err
+	}
+
+	*l = *loaded
+	s.loaded = replayed
+
+	return nil
+}
+
+func (s *EventLogStore) Save(l *TodoList) error {
+	// Saving without loading first must not lose the existing events.
+	if s.loaded == nil {
+		replayed, err := s.replay()
+		if err != nil {
+			return err
+		}
+		s.loaded = replayed
+	}
+
+	events, err := diffEvents(s.loaded, l)
+	if err != nil {
+		return err
+	}
+
+	if len(events) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, e := range append(events, event{Op: "commit"}) {
+		if err := encoder.Encode(e); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.OpenFile(s.Filename, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+
+	// Drop uncommitted events left behind by a crash before appending, so that
+	// they are not committed by this batch.
+	if err := f.Truncate(s.validSize); err != nil {
+		f.Close()
+		return err
+	}
+
+	// A single write keeps the batch together; Sync makes it durable.
+	if _, err := f.WriteAt(buf.Bytes(), s.validSize); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	saved, err := copyList(l)
+	if err != nil {
+		return err
+	}
+	s.loaded = saved
+	s.validSize += int64(buf.Len())
+
+	return nil
+}
+
+// Rebuilds the list by applying every event in the file in order.
+func (s *EventLogStore) replay() (*TodoList, error) {
+	l := &TodoList{}
+	s.validSize = 0
+
+	f, err := os.Open(s.Filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return l, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	pending := []event{}
+	pendingSize := int64(0)
+
+	for lineNumber := 1; ; lineNumber++ {
+		line, err := reader.ReadBytes('\n')
+
+		// A last line without a newline was cut short by a crash; skip it.
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+
+		pendingSize += int64(len(line))
+
+		e := event{}
+		if err := json.Unmarshal(line, &e); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", s.Filename, lineNumber, err)
+		}
+
+		if e.Op != "commit" {
+			pending = append(pending, e)
+			continue
+		}
+
+		for _, e := range pending {
+			applyEvent(l, e)
+		}
+		pending = pending[:0]
+		s.validSize += pendingSize
+		pendingSize = 0
+	}
+
+	l.migrate()
+
+	return l, nil
+}
+
+func applyEvent(l *TodoList, e event) {
+	switch e.Op {
+	case "put":
+		if e.Item == nil {
+			return
+		}
+		if i, err := l.indexOf(e.Item.ID); err == nil {
+			l.Items[i] = *e.Item
+			return
+		}
+		l.Items = append(l.Items, *e.Item)
+
+	case "delete":
+		if i, err := l.indexOf(e.ID); err == nil {
+			l.Items = append(l.Items[:i], l.Items[i+1:]...)
+		}
+
+	case "order":
+		sortByOrder(l.Items, e.Order)
+
+	case "next_id":
+		l.NextID = e.NextID
+
+	case "tombstone":
+		if e.Tombstone != nil {
+			l.addTombstone(*e.Tombstone)
+		}
+	}
+}
+
+// Rewrites the log as the events that create the list, dropping its history.
+func (s *EventLogStore) Compact(l *TodoList) error {
+	events, err := diffEvents(&TodoList{}, l)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, e := range append(events, event{Op: "commit"}) {
+		if err := encoder.Encode(e); err != nil {
+			return err
+		}
+	}
+
+	if err := writeFileAtomic(s.Filename, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+
+	saved, err := copyList(l)
+	if err != nil {
+		return err
+	}
+	s.loaded = saved
+	s.validSize = int64(buf.Len())
+
+	return nil
+}
+
+// Returns the events that turn the old list into the new one.
+func diffEvents(old, new *TodoList) ([]event, error) {
+	events := []event{}
+
+	oldItems := map[int]TodoItem{}
+	for _, item := range old.Items {
+		oldItems[item.ID] = item
+	}
+
+	newIDs := map[int]bool{}
+	for i := range new.Items {
+		item := new.Items[i]
+		newIDs[item.ID] = true
+
+		oldItem, ok := oldItems[item.ID]
+		if ok {
+			same, err := sameItem(oldItem, item)
+			if err != nil {
+				return nil, err
+			}
+			if same {
+				continue
+			}
+		}
+
+		events = append(events, event{Op: "put", Item: &item})
+	}
+
+	// Replaying puts and deletes keeps the old items in place and appends the
+	// new ones, so the order is recorded when the list has another one, such
+	// as after an item is restored in the middle of it.
+	replayed := []int{}
+	for _, item := range old.Items {
+		if !newIDs[item.ID] {
+			events = append(events, event{Op: "delete", ID: item.ID})
+			continue
+		}
+		replayed = append(replayed, item.ID)
+	}
+
+	order := []int{}
+	for _, item := range new.Items {
+		if _, ok := oldItems[item.ID]; !ok {
+			replayed = append(replayed, item.ID)
+		}
+		order = append(order, item.ID)
+	}
+
+	if !equalInts(replayed, order) {
+		events = append(events, event{Op: "order", Order: order})
+	}
+
+	if old.NextID != new.NextID {
+		events = append(events, event{Op: "next_id", NextID: new.NextID})
+	}
+
+	oldTombstones := map[Tombstone]bool{}
+	for _, t := range old.Deleted {
+		oldTombstones[Tombstone{UID: t.UID, At: t.At.UTC()}] = true
+	}
+
+	for i := range new.Deleted {
+		t := new.Deleted[i]
+		if !oldTombstones[Tombstone{UID: t.UID, At: t.At.UTC()}] {
+			events = append(events, event{Op: "tombstone", Tombstone: &t})
+		}
+	}
+
+	return events, nil
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Reports whether two items are stored the same way. Comparing the encoded
+// items ignores differences that do not survive a round trip, such as the
+// monotonic clock reading of a time.
+func sameItem(a, b TodoItem) (bool, error) {
+	encodedA, err := json.Marshal(a)
+	if err != nil {
+		return false, err
+	}
+
+	encodedB, err := json.Marshal(b)
+	if err != nil {
+		return false, err
+	}
+
+	return bytes.Equal(encodedA, encodedB), nil
+}
+
+// Returns a deep copy of the list so that later changes to either one do not
+// affect the other.
+func copyList(l *TodoList) (*TodoList, error) {
+	data, err := json.Marshal(l)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &TodoList{}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
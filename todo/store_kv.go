@@ -0,0 +1,442 @@
+package todo
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Stores the list in a single-file key/value database, similar in spirit to
+// BoltDB but built only on the standard library.
+//
+// Every item is stored under its own key, so saving a change writes only the
+// items that changed. The file is a header followed by a log of records:
+//
+//	op (1 byte) | key length (uvarint) | value length (uvarint) | key | value | CRC-32 (4 bytes)
+//
+// The records of each Save end with a commit record and only take effect once
+// it is read, so a save is applied either completely or not at all. The latest
+// record of a key wins. A record that was cut short by a crash fails its
+// checksum and is discarded along with anything after it, while a damaged
+// record followed by more data makes loading fail rather than lose the records
+// after it. When most records are outdated, the file is compacted by rewriting
+// only the live keys. The order of the items is kept under a key of its own.
+type KVStore struct {
+	Filename string
+
+	data      map[string][]byte // the live keys after the last Load or Save
+	validSize int64             // size of the file up to the last valid record
+	records   int               // number of records in the file
+}
+
+const (
+	kvHeader   = "TODOKV1\n"
+	kvPut      = 1
+	kvDelete   = 2
+	kvCommit   = 3
+	kvNextID   = "meta/next_id"
+	kvOrder    = "meta/order"
+	kvItemPref = "item/"
+	kvDeadPref = "tombstone/"
+
+	// Compact once the file holds this many times more records than keys.
+	kvCompactRatio = 4
+)
+
+func kvItemKey(id int) string {
+	// Zero padding keeps the keys sorted by ID.
+	return fmt.Sprintf("%s%010d", kvItemPref, id)
+}
+
+func (s *KVStore) Load(l *TodoList) error {
+	if err := s.read(); err != nil {
+		return err
+	}
+
+	list := TodoList{}
+	order := []int{}
+
+	keys := make([]string, 0, len(s.data))
+	for key := range s.data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := s.data[key]
+
+		switch {
+		case key == kvNextID:
+			n, err := strconv.Atoi(string(value))
+			if err != nil {
+				return fmt.Errorf("%s: %s: %w", s.Filename, key, err)
+			}
+			list.NextID = n
+
+		case key == kvOrder:
+			if err := json.Unmarshal(value, &order); err != nil {
+				return fmt.Errorf("%s: %s: %w", s.Filename, key, err)
+			}
+
+		case strings.HasPrefix(key, kvItemPref):
+			item := TodoItem{}
+			if err := json.Unmarshal(value, &item); err != nil {
+				return fmt.Errorf("%s: %s: %w", s.Filename, key, err)
+			}
+			list.Items = append(list.Items, item)
+
+		case strings.HasPrefix(key, kvDeadPref):
+			t := Tombstone{}
+			if err := json.Unmarshal(value, &t); err != nil {
+				return fmt.Errorf("%s: %s: %w", s.Filename, key, err)
+			}
+			list.Deleted = append(list.Deleted, t)
+		}
+	}
+
+	sortByOrder(list.Items, order)
+	list.migrate()
+	*l = list
+
+	return nil
+}
+
+func (s *KVStore) Save(l *TodoList) error {
+	// Saving without loading first must not lose the existing keys.
+	if s.data == nil {
+		if err := s.read(); err != nil {
+			return err
+		}
+	}
+
+	next, err := kvData(l)
+	if err != nil {
+		return err
+	}
+
+	var records bytes.Buffer
+	count := 0
+
+	// Sorting keeps the file deterministic.
+	for _, key := range sortedKeys(next) {
+		if old, ok := s.data[key]; !ok || !bytes.Equal(old, next[key]) {
+			writeKVRecord(&records, kvPut, key, next[key])
+			count++
+		}
+	}
+
+	for _, key := range sortedKeys(s.data) {
+		if _, ok := next[key]; !ok {
+			writeKVRecord(&records, kvDelete, key, nil)
+			count++
+		}
+	}
+
+	if count == 0 {
+		return nil
+	}
+
+	writeKVRecord(&records, kvCommit, "", nil)
+	count++
+
+	if s.records+count > kvCompactRatio*len(next) {
+		return s.compact(next)
+	}
+
+	if err := s.appendRecords(records.Bytes()); err != nil {
+		return err
+	}
+
+	s.data = next
+	s.records += count
+
+	return nil
+}
+
+// Rewrites the file with only the live keys of the list.
+func (s *KVStore) Compact(l *TodoList) error {
+	data, err := kvData(l)
+	if err != nil {
+		return err
+	}
+
+	return s.compact(data)
+}
+
+// Returns the keys and values that store the list.
+func kvData(l *TodoList) (map[string][]byte, error) {
+	next := map[string][]byte{kvNextID: []byte(strconv.Itoa(l.NextID))}
+	order := []int{}
+	for _, item := range l.Items {
+		value, err := json.Marshal(item)
+		if err != nil {
+			return nil, err
+		}
+		next[kvItemKey(item.ID)] = value
+		order = append(order, item.ID)
+	}
+
+	value, err := json.Marshal(order)
+	if err != nil {
+		return nil, err
+	}
+	next[kvOrder] = value
+
+	for _, t := range l.Deleted {
+		value, err := json.Marshal(t)
+		if err != nil {
+			return nil, err
+		}
+		next[kvDeadPref+t.UID] = value
+	}
+
+	return next, nil
+}
+
+// Reads every valid record of the file into memory.
+func (s *KVStore) read() error {
+	s.data = map[string][]byte{}
+	s.validSize = 0
+	s.records = 0
+
+	f, err := os.Open(s.Filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+
+	// Records that wait for the commit record of their batch.
+	type change struct {
+		op    byte
+		key   string
+		value []byte
+	}
+	pending := []change{}
+	pendingSize := int64(0)
+
+	header := make([]byte, len(kvHeader))
+	if _, err := io.ReadFull(reader, header); err != nil {
+		// An empty file or a header cut short by a crash holds no records.
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil
+		}
+		return err
+	}
+
+	if string(header) != kvHeader {
+		return fmt.Errorf("%s: %w", s.Filename, ErrInvalidStoreFile)
+	}
+	s.validSize = int64(len(kvHeader))
+
+	for {
+		op, key, value, size, err := readKVRecord(reader)
+		if errors.Is(err, errKVCorrupt) {
+			// A damaged record is only a torn write when it ends the file.
+			if _, peekErr := reader.Peek(1); peekErr == nil {
+				return fmt.Errorf("%s: %w: %v at offset %d", s.Filename, ErrInvalidStoreFile, err, s.validSize+pendingSize)
+			}
+			err = io.EOF
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, errKVTorn) {
+				// Ignore a torn record at the end of the file, and the
+				// uncommitted records before it.
+				return nil
+			}
+			return err
+		}
+
+		pendingSize += size
+
+		if op != kvCommit {
+			pending = append(pending, change{op: op, key: key, value: value})
+			continue
+		}
+
+		for _, c := range pending {
+			switch c.op {
+			case kvPut:
+				s.data[c.key] = c.value
+			case kvDelete:
+				delete(s.data, c.key)
+			}
+		}
+
+		s.validSize += pendingSize
+		s.records += len(pending) + 1
+		pending = pending[:0]
+		pendingSize = 0
+	}
+}
+
+// Appends the encoded records after the last valid record.
+func (s *KVStore) appendRecords(records []byte) error {
+	f, err := os.OpenFile(s.Filename, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+
+	if s.validSize == 0 {
+		records = append([]byte(kvHeader), records...)
+	}
+
+	// Drop a torn record left behind by a crash before appending.
+	if err := f.Truncate(s.validSize); err != nil {
+		f.Close()
+		return err
+	}
+
+	if _, err := f.WriteAt(records, s.validSize); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	s.validSize += int64(len(records))
+
+	return nil
+}
+
+// Rewrites the file with only the live keys.
+func (s *KVStore) compact(data map[string][]byte) error {
+	var buf bytes.Buffer
+	buf.WriteString(kvHeader)
+
+	for _, key := range sortedKeys(data) {
+		writeKVRecord(&buf, kvPut, key, data[key])
+	}
+	writeKVRecord(&buf, kvCommit, "", nil)
+
+	if err := writeFileAtomic(s.Filename, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+
+	s.data = data
+	s.validSize = int64(buf.Len())
+	s.records = len(data) + 1
+
+	return nil
+}
+
+// Returned when a record fails its checksum or is incomplete.
+var (
+	errKVCorrupt = errors.New("corrupt record")
+	errKVTorn    = errors.New("incomplete record")
+)
+
+func writeKVRecord(buf *bytes.Buffer, op byte, key string, value []byte) {
+	var record bytes.Buffer
+
+	record.WriteByte(op)
+	record.Write(uvarint(uint64(len(key))))
+	record.Write(uvarint(uint64(len(value))))
+	record.WriteString(key)
+	record.Write(value)
+
+	checksum := make([]byte, 4)
+	binary.BigEndian.PutUint32(checksum, crc32.ChecksumIEEE(record.Bytes()))
+	record.Write(checksum)
+
+	buf.Write(record.Bytes())
+}
+
+func uvarint(n uint64) []byte {
+	buf := make([]byte, binary.MaxVarintLen64)
+	return buf[:binary.PutUvarint(buf, n)]
+}
+
+// Reads a single record and returns its size in bytes.
+func readKVRecord(reader *bufio.Reader) (byte, string, []byte, int64, error) {
+	var record bytes.Buffer
+
+	op, err := reader.ReadByte()
+	if err != nil {
+		return 0, "", nil, 0, err
+	}
+	record.WriteByte(op)
+
+	lengths := [2]uint64{}
+	for i := range lengths {
+		n, err := binary.ReadUvarint(reader)
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return 0, "", nil, 0, errKVTorn
+		}
+		if err != nil {
+			return 0, "", nil, 0, errKVCorrupt
+		}
+		lengths[i] = n
+		record.Write(uvarint(n))
+	}
+
+	// Guard against allocating huge buffers for garbage lengths.
+	if lengths[0]+lengths[1] > 1<<30 {
+		return 0, "", nil, 0, errKVCorrupt
+	}
+
+	payload := make([]byte, lengths[0]+lengths[1]+4)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		return 0, "", nil, 0, errKVTorn
+	}
+
+	body := payload[:len(payload)-4]
+	record.Write(body)
+
+	if crc32.ChecksumIEEE(record.Bytes()) != binary.BigEndian.Uint32(payload[len(body):]) {
+		return 0, "", nil, 0, errKVCorrupt
+	}
+
+	key := string(body[:lengths[0]])
+	value := body[lengths[0]:]
+
+	return op, key, value, int64(record.Len() + 4), nil
+}
+
+// Sorts the items in the order of their IDs, placing the items missing from
+// it, as in files written before the order was kept, last by ID.
+func sortByOrder(items []TodoItem, order []int) {
+	position := map[int]int{}
+	for i, id := range order {
+		position[id] = i
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		pi, oki := position[items[i].ID]
+		pj, okj := position[items[j].ID]
+		if oki != okj {
+			return oki
+		}
+		return pi < pj
+	})
+}
+
+func sortedKeys(m map[string][]byte) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,363 @@
+package todo_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"mnishiguchi.com/todo"
+)
+
+// Returns the tasks of the items in order.
+func tasksOf(l todo.TodoList) []string {
+	tasks := []string{}
+	for _, item := range l.Items {
+		tasks = append(tasks, item.Task)
+	}
+	return tasks
+}
+
+func TestStores(t *testing.T) {
+	for _, kind := range []string{todo.StoreJSON, todo.StoreEventLog, todo.StoreKV} {
+		t.Run(kind, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), todo.DefaultFilename(kind))
+
+			store, err := todo.NewStore(kind, filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Loading a missing file gives an empty list.
+			list := todo.TodoList{}
+			if err := store.Load(&list); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(list.Items) != 0 {
+				t.Fatalf("Expected an empty list, got %+v instead", list)
+			}
+
+			list.Add("Task 1 +backend")
+			list.Add("Task 2")
+			list.Add("Task 3")
+			if err := store.Save(&list); err != nil {
+				t.Fatal(err)
+			}
+
+			before, err := list.Clone()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := list.Complete(1); err != nil {
+				t.Fatal(err)
+			}
+			if err := list.Delete(3); err != nil {
+				t.Fatal(err)
+			}
+			list.Stamp(before, time.Now())
+			if err := store.Save(&list); err != nil {
+				t.Fatal(err)
+			}
+
+			// A fresh store sees every change.
+			reopened, err := todo.NewStore(kind, filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			loaded := todo.TodoList{}
+			if err := reopened.Load(&loaded); err != nil {
+				t.Fatal(err)
+			}
+
+			if expected := []string{"Task 1", "Task 2"}; !reflect.DeepEqual(tasksOf(loaded), expected) {
+				t.Errorf("Expected tasks %q, got %q instead", expected, tasksOf(loaded))
+			}
+
+			if !loaded.Items[0].Done || !reflect.DeepEqual(loaded.Items[0].Projects, []string{"backend"}) {
+				t.Errorf("Expected the first item to be saved with its fields, got %+v instead", loaded.Items[0])
+			}
+
+			if len(loaded.Deleted) != 1 || loaded.Deleted[0].UID != before.Items[2].UID {
+				t.Errorf("Expected a tombstone for %q, got %+v instead", before.Items[2].UID, loaded.Deleted)
+			}
+
+			// The deleted ID is not reused after reloading.
+			loaded.Add("Task 4")
+			if id := loaded.Items[2].ID; id != 4 {
+				t.Errorf("Expected ID %d, got %d instead", 4, id)
+			}
+		})
+	}
+}
+
+func TestNewStoreInvalid(t *testing.T) {
+	if _, err := todo.NewStore("sqlite", "todo.db"); !errors.Is(err, todo.ErrInvalidStore) {
+		t.Errorf("Expected error %q, got %v instead", todo.ErrInvalidStore, err)
+	}
+}
+
+// Simulates a crash in the middle of a save for the stores that append.
+func TestStoresTornWrite(t *testing.T) {
+	for _, kind := range []string{todo.StoreEventLog, todo.StoreKV} {
+		t.Run(kind, func(t *testing.T) {
+			testTornWrite(t, kind)
+		})
+	}
+}
+
+func testTornWrite(t *testing.T, kind string) {
+	filename := filepath.Join(t.TempDir(), todo.DefaultFilename(kind))
+	store, err := todo.NewStore(kind, filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list := todo.TodoList{}
+	list.Add("Task 1")
+	if err := store.Save(&list); err != nil {
+		t.Fatal(err)
+	}
+
+	list.Add("Task 2")
+	if err := store.Save(&list); err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulate a crash in the middle of the last write.
+	info, err := os.Stat(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(filename, info.Size()-3); err != nil {
+		t.Fatal(err)
+	}
+
+	recovered, err := todo.NewStore(kind, filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := todo.TodoList{}
+	if err := recovered.Load(&loaded); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(loaded.Items) != 1 || loaded.Items[0].Task != "Task 1" {
+		t.Fatalf("Expected only the first task to survive, got %+v instead", loaded.Items)
+	}
+
+	// Saving again replaces the torn record.
+	loaded.Add("Task 3")
+	if err := recovered.Save(&loaded); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := todo.NewStore(kind, filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded := todo.TodoList{}
+	if err := reopened.Load(&reloaded); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(reloaded.Items) != 2 || reloaded.Items[1].Task != "Task 3" || reloaded.Items[1].ID != 2 {
+		t.Errorf("Expected Task 1 and Task 3, got %+v instead", reloaded.Items)
+	}
+}
+
+func TestKVStoreCompaction(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "todo.kv")
+	store := &todo.KVStore{Filename: filename}
+
+	list := todo.TodoList{}
+	list.Add("Task 1")
+
+	// Many updates to the same item would grow the file without compaction.
+	for i := 0; i < 200; i++ {
+		list.Items[0].Task = "Task 1 update " + string(rune('a'+i%26))
+		if err := store.Save(&list); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	info, err := os.Stat(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Size() > 4096 {
+		t.Errorf("Expected the file to be compacted, got %d bytes", info.Size())
+	}
+
+	loaded := todo.TodoList{}
+	if err := (&todo.KVStore{Filename: filename}).Load(&loaded); err != nil {
+		t.Fatal(err)
+	}
+
+	if loaded.Items[0].Task != list.Items[0].Task {
+		t.Errorf("Expected %q, got %q instead", list.Items[0].Task, loaded.Items[0].Task)
+	}
+}
+
+func TestStoresKeepOrder(t *testing.T) {
+	for _, kind := range []string{todo.StoreJSON, todo.StoreEventLog, todo.StoreKV} {
+		t.Run(kind, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), todo.DefaultFilename(kind))
+
+			store, err := todo.NewStore(kind, filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			list := todo.TodoList{}
+			list.Add("Task 1")
+			list.Add("Task 2")
+			list.Add("Task 3")
+			list.Items[0], list.Items[2] = list.Items[2], list.Items[0]
+			if err := store.Save(&list); err != nil {
+				t.Fatal(err)
+			}
+
+			// Deleting an item and undoing it puts the item back in place.
+			journal := todo.Journal{}
+			if err := journal.Track("rm 2", &list, func(l *todo.TodoList) error { return l.Delete(2) }); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.Save(&list); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := journal.Undo(&list); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.Save(&list); err != nil {
+				t.Fatal(err)
+			}
+
+			reopened, err := todo.NewStore(kind, filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			loaded := todo.TodoList{}
+			if err := reopened.Load(&loaded); err != nil {
+				t.Fatal(err)
+			}
+
+			if ids := idsOf(loaded.Items); !reflect.DeepEqual(ids, []int{3, 2, 1}) {
+				t.Errorf("Expected %v, got %v instead", []int{3, 2, 1}, ids)
+			}
+		})
+	}
+}
+
+func TestKVStoreCorruptRecord(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "todo.kv")
+	store := &todo.KVStore{Filename: filename}
+
+	list := todo.TodoList{}
+	list.Add("Task 1")
+	if err := store.Save(&list); err != nil {
+		t.Fatal(err)
+	}
+	list.Add("Task 2")
+	if err := store.Save(&list); err != nil {
+		t.Fatal(err)
+	}
+
+	// Damage a byte of the first task, which is followed by more records.
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	i := strings.Index(string(data), "Task 1")
+	data[i] = 'X'
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := todo.TodoList{}
+	if err := (&todo.KVStore{Filename: filename}).Load(&loaded); !errors.Is(err, todo.ErrInvalidStoreFile) {
+		t.Errorf("Expected %q, got %v instead", todo.ErrInvalidStoreFile, err)
+	}
+
+	// Saving does not drop the records after the damaged one either.
+	if err := (&todo.KVStore{Filename: filename}).Save(&list); !errors.Is(err, todo.ErrInvalidStoreFile) {
+		t.Errorf("Expected %q saving, got %v instead", todo.ErrInvalidStoreFile, err)
+	}
+}
+
+func TestStoresCompact(t *testing.T) {
+	for _, kind := range []string{todo.StoreEventLog, todo.StoreKV} {
+		t.Run(kind, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), todo.DefaultFilename(kind))
+
+			store, err := todo.NewStore(kind, filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			list := todo.TodoList{}
+			for i := 0; i < 20; i++ {
+				list.Add("Task")
+				if err := store.Save(&list); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for i := 0; i < 19; i++ {
+				if err := list.Delete(list.Items[0].ID); err != nil {
+					t.Fatal(err)
+				}
+			}
+			list.Deleted = []todo.Tombstone{{UID: "gone", At: time.Now()}}
+			if err := store.Save(&list); err != nil {
+				t.Fatal(err)
+			}
+
+			before, err := os.Stat(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := store.(todo.Compacter).Compact(&list); err != nil {
+				t.Fatal(err)
+			}
+
+			after, err := os.Stat(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The key/value store may have compacted itself while saving.
+			if after.Size() > before.Size() || kind == todo.StoreEventLog && after.Size() == before.Size() {
+				t.Errorf("Expected the file to shrink from %d bytes, got %d bytes instead", before.Size(), after.Size())
+			}
+
+			// Saving after compacting appends to the new file.
+			list.Add("Task 21")
+			if err := store.Save(&list); err != nil {
+				t.Fatal(err)
+			}
+
+			reopened, err := todo.NewStore(kind, filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			loaded := todo.TodoList{}
+			if err := reopened.Load(&loaded); err != nil {
+				t.Fatal(err)
+			}
+
+			if ids := idsOf(loaded.Items); !reflect.DeepEqual(ids, []int{20, 21}) || loaded.NextID != 22 || len(loaded.Deleted) != 1 {
+				t.Errorf("Expected items %v with next ID 22 and a tombstone, got %v, %d and %v instead", []int{20, 21}, ids, loaded.NextID, loaded.Deleted)
+			}
+		})
+	}
+}
@@ -0,0 +1,371 @@
+package todo
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Records that the item with the UID was deleted at a given time, so that the
+// deletion reaches the other copies of the list when syncing.
+type Tombstone struct {
+	UID      string
+	At       time.Time
+	Archived bool `json:",omitempty"` // the item was archived, which stays local
+}
+
+// How long the tombstones of deleted items are kept. A copy of the list that
+// has not synced for longer may bring the deleted items back.
+const TombstoneRetention = 90 * 24 * time.Hour
+
+// What a merge changed in a list.
+type MergeReport struct {
+	Added   []TodoItem
+	Updated []ItemUpdate
+	Deleted []TodoItem
+}
+
+// An item changed by a merge, with the names of the fields that changed.
+type ItemUpdate struct {
+	Item   TodoItem
+	Fields []string
+}
+
+// A field that is compared and merged on its own. The value is a normalized
+// form of the field used to detect changes.
+type syncField struct {
+	name  string
+	value func(TodoItem) string
+	set   func(dst *TodoItem, src TodoItem)
+}
+
+// The fields kept in sync between the copies of a list. The links between
+// items (ParentID, BlockedBy and SeriesID) refer to IDs that differ from one
+// copy to another, so they stay local.
+var syncFields = []syncField{
+	{
+		name:  "task",
+		value: func(i TodoItem) string { return i.Task },
+		set:   func(dst *TodoItem, src TodoItem) { dst.Task = src.Task },
+	},
+	{
+		name:  "done",
+		value: func(i TodoItem) string { return fmt.Sprint(i.Done, " ", formatStamp(i.CompletedAt)) },
+		set:   func(dst *TodoItem, src TodoItem) { dst.Done, dst.CompletedAt = src.Done, src.CompletedAt },
+	},
+	{
+		name:  "priority",
+		value: func(i TodoItem) string { return i.Priority },
+		set:   func(dst *TodoItem, src TodoItem) { dst.Priority = src.Priority },
+	},
+	{
+		name:  "due",
+		value: func(i TodoItem) string { return formatStamp(i.Due) },
+		set:   func(dst *TodoItem, src TodoItem) { dst.Due = src.Due },
+	},
+	{
+		name:  "projects",
+		value: func(i TodoItem) string { return strings.Join(i.Projects, " ") },
+		set:   func(dst *TodoItem, src TodoItem) { dst.Projects = append([]string(nil), src.Projects...) },
+	},
+	{
+		name:  "contexts",
+		value: func(i TodoItem) string { return strings.Join(i.Contexts, " ") },
+		set:   func(dst *TodoItem, src TodoItem) { dst.Contexts = append([]string(nil), src.Contexts...) },
+	},
+	{
+		name:  "recur",
+		value: func(i TodoItem) string { return i.Recur },
+		set:   func(dst *TodoItem, src TodoItem) { dst.Recur = src.Recur },
+	},
+	{
+		name: "sessions",
+		value: func(i TodoItem) string {
+			sessions := []string{}
+			for _, s := range i.Sessions {
+				sessions = append(sessions, formatStamp(s.Start)+"/"+formatStamp(s.End))
+			}
+			return strings.Join(sessions, " ")
+		},
+		set: func(dst *TodoItem, src TodoItem) { dst.Sessions = append([]Session(nil), src.Sessions...) },
+	},
+}
+
+// Returns a deep copy of the list.
+func (l *TodoList) Clone() (*TodoList, error) {
+	return copyList(l)
+}
+
+// Records when the fields of the items last changed, comparing the list with
+// the way it was before a change. Changed fields are stamped with the time
+// now unless the change brought a later stamp of its own, as a merge does,
+// and the items that are gone leave a tombstone. Items are matched by ID.
+//
+// An item that comes back after its deletion, as an undo does, is stamped
+// anew so that its tombstone does not delete it again, while the items
+// deleted by a merge keep the tombstone they were deleted with.
+func (l *TodoList) Stamp(before *TodoList, now time.Time) {
+	previous := map[int]TodoItem{}
+	for _, item := range before.Items {
+		previous[item.ID] = item
+	}
+
+	for i := range l.Items {
+		item := &l.Items[i]
+		if item.UID == "" {
+			item.UID = newUID()
+		}
+
+		prev, ok := previous[item.ID]
+		delete(previous, item.ID)
+
+		restored := false
+		if at, deleted := l.tombstoneAt(item.UID); !ok && deleted && !item.lastModified().After(at) {
+			restored = true
+			l.removeTombstone(item.UID)
+		}
+
+		for _, f := range syncFields {
+			if ok && f.value(prev) == f.value(*item) {
+				continue
+			}
+
+			stamped := item.Modified[f.name]
+			if restored || ok && !stamped.After(prev.Modified[f.name]) || !ok && stamped.IsZero() {
+				item.setModified(f.name, now)
+			}
+		}
+	}
+
+	for _, item := range before.Items {
+		if _, gone := previous[item.ID]; !gone || item.UID == "" {
+			continue
+		}
+
+		if at, deleted := l.tombstoneAt(item.UID); deleted && !item.lastModified().After(at) {
+			continue
+		}
+
+		l.addTombstone(Tombstone{UID: item.UID, At: now})
+	}
+}
+
+// Merges another copy of the list into this one. Each field takes the value
+// with the latest modification time, and a deletion wins over an item that was
+// not modified after it. Items new to the list get new IDs and no links.
+// Merging the result back into the other copy makes both copies the same.
+func (l *TodoList) Merge(items []TodoItem, deleted []Tombstone) MergeReport {
+	report := MergeReport{}
+
+	for _, t := range deleted {
+		l.addTombstone(t)
+	}
+	tombstones := map[string]time.Time{}
+	for _, t := range l.Deleted {
+		tombstones[t.UID] = t.At
+	}
+
+	for _, incoming := range items {
+		if incoming.UID == "" {
+			continue
+		}
+
+		index := l.indexOfUID(incoming.UID)
+		if index < 0 {
+			if at, ok := tombstones[incoming.UID]; ok && !incoming.lastModified().After(at) {
+				continue
+			}
+
+			item := incoming
+			item.ID = l.nextID()
+			item.ParentID = 0
+			item.SeriesID = 0
+			item.BlockedBy = nil
+			l.Items = append(l.Items, item)
+			report.Added = append(report.Added, item)
+			continue
+		}
+
+		local := &l.Items[index]
+		fields := []string{}
+
+		for _, f := range syncFields {
+			ours, theirs := local.modifiedAt(f.name), incoming.modifiedAt(f.name)
+			ourValue, theirValue := f.value(*local), f.value(incoming)
+
+			if ourValue == theirValue {
+				if theirs.After(ours) {
+					local.setModified(f.name, theirs)
+				}
+				continue
+			}
+
+			// Equal times are settled by the values, so that both copies
+			// pick the same one.
+			if theirs.After(ours) || theirs.Equal(ours) && theirValue > ourValue {
+				f.set(local, incoming)
+				local.setModified(f.name, theirs)
+				fields = append(fields, f.name)
+			}
+		}
+
+		if len(fields) > 0 {
+			report.Updated = append(report.Updated, ItemUpdate{Item: *local, Fields: fields})
+		}
+	}
+
+	for _, t := range deleted {
+		index := l.indexOfUID(t.UID)
+		if index < 0 || l.Items[index].lastModified().After(tombstones[t.UID]) {
+			continue
+		}
+
+		item := l.Items[index]
+		report.Deleted = append(report.Deleted, item)
+		l.Delete(item.ID)
+	}
+
+	return report
+}
+
+// Reports whether the merge changed nothing.
+func (r MergeReport) IsEmpty() bool {
+	return len(r.Added) == 0 && len(r.Updated) == 0 && len(r.Deleted) == 0
+}
+
+// Prints one line per change, implementing the fmt.Stringer interface.
+func (r MergeReport) String() string {
+	formatted := ""
+
+	for _, item := range r.Added {
+		formatted += fmt.Sprintf("added: %s\n", item.Task)
+	}
+
+	for _, u := range r.Updated {
+		formatted += fmt.Sprintf("updated: %s (%s)\n", u.Item.Task, strings.Join(u.Fields, ", "))
+	}
+
+	for _, item := range r.Deleted {
+		formatted += fmt.Sprintf("deleted: %s\n", item.Task)
+	}
+
+	return formatted
+}
+
+// Returns when the field was last modified. Items written before fields were
+// stamped count as modified when they were created.
+func (i TodoItem) modifiedAt(field string) time.Time {
+	if at, ok := i.Modified[field]; ok {
+		return at
+	}
+
+	return i.CreatedAt
+}
+
+// Returns when any field of the item was last modified.
+func (i TodoItem) lastModified() time.Time {
+	last := time.Time{}
+	for _, f := range syncFields {
+		if at := i.modifiedAt(f.name); at.After(last) {
+			last = at
+		}
+	}
+
+	return last
+}
+
+// Sets when the field was last modified. The map is copied rather than
+// updated, since copies of an item share it.
+func (i *TodoItem) setModified(field string, at time.Time) {
+	modified := map[string]time.Time{}
+	for k, v := range i.Modified {
+		modified[k] = v
+	}
+	modified[field] = at
+	i.Modified = modified
+}
+
+// Adds a tombstone, keeping the latest time for each UID.
+func (l *TodoList) addTombstone(t Tombstone) {
+	for i := range l.Deleted {
+		if l.Deleted[i].UID == t.UID {
+			if t.At.After(l.Deleted[i].At) {
+				l.Deleted[i].At = t.At
+			}
+			return
+		}
+	}
+
+	l.Deleted = append(l.Deleted, t)
+}
+
+// Returns the time of the tombstone of the UID, if any.
+func (l *TodoList) tombstoneAt(uid string) (time.Time, bool) {
+	for _, t := range l.Deleted {
+		if t.UID == uid {
+			return t.At, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// Removes the tombstone of the UID, if any.
+func (l *TodoList) removeTombstone(uid string) {
+	for i, t := range l.Deleted {
+		if t.UID == uid {
+			l.Deleted = append(l.Deleted[:i:i], l.Deleted[i+1:]...)
+			return
+		}
+	}
+}
+
+// Removes the tombstones of the items deleted before the time. The tombstones
+// of archived items are kept; see Remote.Sync.
+func (l *TodoList) PruneTombstones(before time.Time) {
+	l.filterTombstones(func(t Tombstone) bool { return t.Archived || !t.At.Before(before) })
+}
+
+// Keeps the tombstones for which keep returns true.
+func (l *TodoList) filterTombstones(keep func(Tombstone) bool) {
+	var kept []Tombstone
+	for _, t := range l.Deleted {
+		if keep(t) {
+			kept = append(kept, t)
+		}
+	}
+
+	l.Deleted = kept
+}
+
+// Returns the index of the item with the UID, or -1 when there is none.
+func (l *TodoList) indexOfUID(uid string) int {
+	for i, item := range l.Items {
+		if item.UID == uid {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Returns a random identifier that is unique across the copies of a list.
+func newUID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// Formats a time in UTC so that the same instant always gives the same text.
+func formatStamp(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.UTC().Format(time.RFC3339Nano)
+}
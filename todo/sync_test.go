@@ -0,0 +1,277 @@
+package todo_test
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"mnishiguchi.com/todo"
+)
+
+var syncStart = time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+
+// Applies the change to the list and stamps it as made at the given minute.
+func change(t *testing.T, l *todo.TodoList, minute int, f func(*todo.TodoList) error) {
+	t.Helper()
+
+	before, err := l.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f(l); err != nil {
+		t.Fatal(err)
+	}
+
+	l.Stamp(before, syncStart.Add(time.Duration(minute)*time.Minute))
+}
+
+// Returns two copies of a list of two items.
+func replicas(t *testing.T) (*todo.TodoList, *todo.TodoList) {
+	t.Helper()
+
+	a := &todo.TodoList{}
+	change(t, a, 0, func(l *todo.TodoList) error {
+		l.Add("Ship release")
+		l.Add("Write changelog")
+		return nil
+	})
+
+	b := &todo.TodoList{}
+	b.Merge(a.Items, a.Deleted)
+
+	return a, b
+}
+
+func TestStamp(t *testing.T) {
+	l := &todo.TodoList{}
+	change(t, l, 0, func(l *todo.TodoList) error {
+		l.Add("Ship release")
+		l.Add("Write changelog")
+		return nil
+	})
+	change(t, l, 5, func(l *todo.TodoList) error {
+		l.Items[0].Priority = "A"
+		return l.Delete(2)
+	})
+
+	item := l.Items[0]
+	if at := item.Modified["priority"]; !at.Equal(syncStart.Add(5 * time.Minute)) {
+		t.Errorf("Expected the priority stamped at %s, got %s instead", syncStart.Add(5*time.Minute), at)
+	}
+	if at := item.Modified["task"]; !at.Equal(syncStart) {
+		t.Errorf("Expected the task stamped at %s, got %s instead", syncStart, at)
+	}
+
+	if len(l.Deleted) != 1 || !l.Deleted[0].At.Equal(syncStart.Add(5*time.Minute)) {
+		t.Errorf("Expected a tombstone for the deleted item, got %+v instead", l.Deleted)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	testCases := []struct {
+		name     string
+		changeA  func(*todo.TodoList) error
+		changeB  func(*todo.TodoList) error
+		expected string
+	}{
+		{
+			name:     "DifferentFields",
+			changeA:  func(l *todo.TodoList) error { return l.Complete(1) },
+			changeB:  func(l *todo.TodoList) error { l.Items[0].Priority = "A"; return nil },
+			expected: "false Write changelog\ntrue (A) Ship release\n",
+		},
+		{
+			name:     "SameFieldLaterWins",
+			changeA:  func(l *todo.TodoList) error { return l.Edit(1, "Ship release 1.0") },
+			changeB:  func(l *todo.TodoList) error { return l.Edit(1, "Ship release 2.0") },
+			expected: "false Ship release 2.0\nfalse Write changelog\n",
+		},
+		{
+			name:     "DeletedBeforeEdit",
+			changeA:  func(l *todo.TodoList) error { return l.Delete(2) },
+			changeB:  func(l *todo.TodoList) error { return l.Edit(2, "Write the changelog") },
+			expected: "false Ship release\nfalse Write the changelog\n",
+		},
+		{
+			name:     "DeletedAfterEdit",
+			changeA:  func(l *todo.TodoList) error { return l.Edit(2, "Write the changelog") },
+			changeB:  func(l *todo.TodoList) error { return l.Delete(2) },
+			expected: "false Ship release\n",
+		},
+		{
+			name:     "Added",
+			changeA:  func(l *todo.TodoList) error { l.Add("Book the room"); return nil },
+			changeB:  func(l *todo.TodoList) error { l.Add("Tag the commit"); return nil },
+			expected: "false Book the room\nfalse Ship release\nfalse Tag the commit\nfalse Write changelog\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			a, b := replicas(t)
+			change(t, a, 1, tc.changeA)
+			change(t, b, 2, tc.changeB)
+
+			// Sync a with b, then b with the result, the way a client syncs
+			// with the server.
+			b.Merge(a.Items, a.Deleted)
+			a.Merge(b.Items, b.Deleted)
+
+			if res := synced(a); res != tc.expected {
+				t.Errorf("Expected %q, got %q instead", tc.expected, res)
+			}
+
+			if res := synced(b); res != tc.expected {
+				t.Errorf("Expected %q in the other copy, got %q instead", tc.expected, res)
+			}
+		})
+	}
+}
+
+func TestMergeReport(t *testing.T) {
+	a, b := replicas(t)
+	change(t, a, 1, func(l *todo.TodoList) error {
+		l.Items[0].Priority = "A"
+		l.Items[0].Projects = []string{"backend"}
+		l.Add("Book the room")
+		return l.Delete(2)
+	})
+
+	report := b.Merge(a.Items, a.Deleted)
+
+	expected := "added: Book the room\n" +
+		"updated: Ship release (priority, projects)\n" +
+		"deleted: Write changelog\n"
+
+	if report.String() != expected {
+		t.Errorf("Expected %q, got %q instead", expected, report.String())
+	}
+
+	// Merging again changes nothing.
+	if report := b.Merge(a.Items, a.Deleted); !report.IsEmpty() {
+		t.Errorf("Expected no changes, got %q instead", report)
+	}
+}
+
+func TestMergeEditAfterDelete(t *testing.T) {
+	server := &todo.TodoList{}
+	a, b := replicas(t)
+	server.Merge(a.Items, a.Deleted)
+
+	change(t, a, 1, func(l *todo.TodoList) error { return l.Delete(2) })
+	change(t, b, 2, func(l *todo.TodoList) error { return l.Edit(2, "Write the changelog") })
+
+	// The deletion reaches the server before the later edit does.
+	syncWith(t, a, server, 3)
+	syncWith(t, b, server, 4)
+	syncWith(t, a, server, 5)
+
+	expected := "false Ship release\nfalse Write the changelog\n"
+	for name, l := range map[string]*todo.TodoList{"a": a, "b": b, "server": server} {
+		if res := synced(l); res != expected {
+			t.Errorf("Expected %q in %s, got %q instead", expected, name, res)
+		}
+	}
+}
+
+func TestMergeRestored(t *testing.T) {
+	server := &todo.TodoList{}
+	a, _ := replicas(t)
+	server.Merge(a.Items, a.Deleted)
+
+	journal := &todo.Journal{}
+	change(t, a, 1, func(l *todo.TodoList) error {
+		return journal.Track("delete: 2", l, func(l *todo.TodoList) error { return l.Delete(2) })
+	})
+	syncWith(t, a, server, 2)
+
+	// Undoing the deletion outlives the tombstone the server already has.
+	change(t, a, 3, func(l *todo.TodoList) error {
+		_, err := journal.Undo(l)
+		return err
+	})
+	syncWith(t, a, server, 4)
+
+	expected := "false Ship release\nfalse Write changelog\n"
+	for name, l := range map[string]*todo.TodoList{"a": a, "server": server} {
+		if res := synced(l); res != expected {
+			t.Errorf("Expected %q in %s, got %q instead", expected, name, res)
+		}
+	}
+}
+
+func TestMergeArchived(t *testing.T) {
+	server := &todo.TodoList{}
+	a, _ := replicas(t)
+	server.Merge(a.Items, a.Deleted)
+
+	change(t, a, 1, func(l *todo.TodoList) error { return l.Complete(2) })
+	syncWith(t, a, server, 2)
+
+	change(t, a, 3, func(l *todo.TodoList) error {
+		_, err := l.Archive(&todo.TodoList{}, time.Now().Add(time.Hour))
+		return err
+	})
+	syncWith(t, a, server, 4)
+
+	// The archived item stays on the server without coming back.
+	if res, expected := synced(a), "false Ship release\n"; res != expected {
+		t.Errorf("Expected %q, got %q instead", expected, res)
+	}
+
+	if res, expected := synced(server), "false Ship release\ntrue Write changelog\n"; res != expected {
+		t.Errorf("Expected %q on the server, got %q instead", expected, res)
+	}
+}
+
+func TestPruneTombstones(t *testing.T) {
+	now := syncStart
+	list := &todo.TodoList{Deleted: []todo.Tombstone{
+		{UID: "old", At: now.AddDate(0, 0, -100)},
+		{UID: "recent", At: now.AddDate(0, 0, -10)},
+		{UID: "archived", At: now.AddDate(0, 0, -100), Archived: true},
+	}}
+
+	list.PruneTombstones(now.Add(-todo.TombstoneRetention))
+
+	uids := []string{}
+	for _, t := range list.Deleted {
+		uids = append(uids, t.UID)
+	}
+
+	if expected := []string{"recent", "archived"}; !reflect.DeepEqual(uids, expected) {
+		t.Errorf("Expected %v, got %v instead", expected, uids)
+	}
+}
+
+// Syncs the client with the server the way the CLI and todo_server do,
+// stamping both at the given minute.
+func syncWith(t *testing.T, client, server *todo.TodoList, minute int) {
+	t.Helper()
+
+	change(t, server, minute, func(l *todo.TodoList) error {
+		state := client.SyncState()
+		l.Merge(state.Items, state.Deleted)
+		return nil
+	})
+	change(t, client, minute, func(l *todo.TodoList) error {
+		l.Merge(server.Items, server.Deleted)
+		return nil
+	})
+}
+
+// Lists the done states and texts of the items in alphabetical order, since
+// the copies of a list may order the items differently.
+func synced(l *todo.TodoList) string {
+	lines := []string{}
+	for _, item := range l.Items {
+		lines = append(lines, fmt.Sprintf("%t %s\n", item.Done, item.Text()))
+	}
+	sort.Strings(lines)
+
+	return strings.Join(lines, "")
+}
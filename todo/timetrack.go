@@ -0,0 +1,260 @@
+package todo
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The format of a time report meant to be read.
+const FormatText = "text"
+
+// A period of work on an item. End is zero while the session is running.
+type Session struct {
+	Start time.Time
+	End   time.Time
+}
+
+// The time spent on an item or a tag within a report.
+type TimeSpent struct {
+	Item  TodoItem // the zero item for a tag
+	Tag   string   // "+project" or "@context", or empty for an item
+	Spent time.Duration
+}
+
+// The time spent on the items between two times, per item and per tag, most
+// time first.
+type TimeReport struct {
+	Since time.Time
+	Until time.Time
+	Items []TimeSpent
+	Tags  []TimeSpent
+	Total time.Duration
+}
+
+// Starts a work session on the item, stopping the session running on another
+// item if any. Returns the ID of the stopped item, or zero.
+func (l *TodoList) Start(id int, now time.Time) (int, error) {
+	index, err := l.indexOf(id)
+	if err != nil {
+		return 0, err
+	}
+
+	if l.Items[index].Done {
+		return 0, fmt.Errorf("%w: %d", ErrItemDone, id)
+	}
+
+	if isRunning(l.Items[index]) {
+		return 0, fmt.Errorf("%w: %d", ErrSessionRunning, id)
+	}
+
+	stopped := 0
+	if running, ok := l.Running(); ok {
+		if err := l.Stop(running.ID, now); err != nil {
+			return 0, err
+		}
+		stopped = running.ID
+	}
+
+	item := &l.Items[index]
+	item.Sessions = append(append([]Session(nil), item.Sessions...), Session{Start: now})
+
+	return stopped, nil
+}
+
+// Stops the work session running on the item.
+func (l *TodoList) Stop(id int, now time.Time) error {
+	index, err := l.indexOf(id)
+	if err != nil {
+		return err
+	}
+
+	if !isRunning(l.Items[index]) {
+		return fmt.Errorf("%w: %d", ErrNoSession, id)
+	}
+
+	l.stopSession(index, now)
+
+	return nil
+}
+
+// Returns the item with a running session, if any.
+func (l *TodoList) Running() (TodoItem, bool) {
+	for _, item := range l.Items {
+		if isRunning(item) {
+			return item, true
+		}
+	}
+
+	return TodoItem{}, false
+}
+
+// Returns the time spent on the items between since and now. A session still
+// running counts until now, and sessions are cut at the edges of the period.
+func (l *TodoList) TimeReport(since, now time.Time) TimeReport {
+	report := TimeReport{Since: since, Until: now}
+	tags := map[string]time.Duration{}
+
+	for _, item := range l.Items {
+		spent := time.Duration(0)
+		for _, s := range item.Sessions {
+			spent += sessionOverlap(s, since, now)
+		}
+
+		if spent == 0 {
+			continue
+		}
+
+		report.Items = append(report.Items, TimeSpent{Item: item, Spent: spent})
+		report.Total += spent
+
+		for _, p := range item.Projects {
+			tags["+"+p] += spent
+		}
+		for _, c := range item.Contexts {
+			tags["@"+c] += spent
+		}
+	}
+
+	for tag, spent := range tags {
+		report.Tags = append(report.Tags, TimeSpent{Tag: tag, Spent: spent})
+	}
+
+	sortSpent(report.Items, func(t TimeSpent) string { return fmt.Sprintf("%010d", t.Item.ID) })
+	sortSpent(report.Tags, func(t TimeSpent) string { return t.Tag })
+
+	return report
+}
+
+// Writes the report in the format: text or csv. The CSV has a row per item, a
+// row per tag and a row for the total, with the time in minutes.
+func (r TimeReport) Write(w io.Writer, format string) error {
+	switch format {
+	case FormatText, "":
+		return r.writeText(w)
+	case FormatCSV:
+		return r.writeCSV(w)
+	}
+
+	return fmt.Errorf("%w: %q", ErrInvalidFormat, format)
+}
+
+func (r TimeReport) writeText(w io.Writer) error {
+	out := fmt.Sprintf("Since %s\n", r.Since.Format("2006-01-02 15:04"))
+
+	if len(r.Items) > 0 {
+		out += "\nItems\n"
+		for _, t := range r.Items {
+			out += fmt.Sprintf("  %8s  %d: %s\n", FormatDuration(t.Spent), t.Item.ID, t.Item.Task)
+		}
+	}
+
+	if len(r.Tags) > 0 {
+		out += "\nTags\n"
+		for _, t := range r.Tags {
+			out += fmt.Sprintf("  %8s  %s\n", FormatDuration(t.Spent), t.Tag)
+		}
+	}
+
+	out += fmt.Sprintf("\nTotal     %s\n", FormatDuration(r.Total))
+
+	_, err := io.WriteString(w, out)
+	return err
+}
+
+func (r TimeReport) writeCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+
+	records := [][]string{{"Kind", "ID", "Name", "Minutes"}}
+	for _, t := range r.Items {
+		records = append(records, []string{"item", strconv.Itoa(t.Item.ID), t.Item.Task, minutes(t.Spent)})
+	}
+	for _, t := range r.Tags {
+		records = append(records, []string{"tag", "", t.Tag, minutes(t.Spent)})
+	}
+	records = append(records, []string{"total", "", "", minutes(r.Total)})
+
+	if err := cw.WriteAll(records); err != nil {
+		return err
+	}
+
+	return cw.Error()
+}
+
+// Formats a duration in hours and minutes, such as 26h05m.
+func FormatDuration(d time.Duration) string {
+	total := int(d.Round(time.Minute) / time.Minute)
+	return fmt.Sprintf("%dh%02dm", total/60, total%60)
+}
+
+// Parses a time in the past: a date in the 2006-01-02 layout, "today" for the
+// start of the day, or a number of days before now such as 7d or 2w.
+func ParseSince(value string, now time.Time) (time.Time, error) {
+	if strings.EqualFold(value, "today") {
+		return startOfDay(now), nil
+	}
+
+	if t, err := time.ParseInLocation(DateLayout, value, now.Location()); err == nil {
+		return t, nil
+	}
+
+	days, err := ParseDays(value)
+	if err != nil || days < 0 {
+		return time.Time{}, fmt.Errorf("%w: %q", ErrInvalidSince, value)
+	}
+
+	return now.AddDate(0, 0, -days), nil
+}
+
+func isRunning(item TodoItem) bool {
+	n := len(item.Sessions)
+	return n > 0 && item.Sessions[n-1].End.IsZero()
+}
+
+// Ends the running session of the item, if any.
+func (l *TodoList) stopSession(index int, now time.Time) {
+	item := &l.Items[index]
+	if !isRunning(*item) {
+		return
+	}
+
+	// Copying the sessions keeps copies of the item from sharing them.
+	item.Sessions = append([]Session(nil), item.Sessions...)
+	item.Sessions[len(item.Sessions)-1].End = now
+}
+
+// Returns the part of the session between since and until, with a running
+// session lasting until then.
+func sessionOverlap(s Session, since, until time.Time) time.Duration {
+	start, end := s.Start, s.End
+	if end.IsZero() || end.After(until) {
+		end = until
+	}
+	if start.Before(since) {
+		start = since
+	}
+
+	if !end.After(start) {
+		return 0
+	}
+
+	return end.Sub(start)
+}
+
+// Sorts by the time spent, most first, then by the key.
+func sortSpent(spent []TimeSpent, key func(TimeSpent) string) {
+	sort.Slice(spent, func(i, j int) bool {
+		if spent[i].Spent != spent[j].Spent {
+			return spent[i].Spent > spent[j].Spent
+		}
+		return key(spent[i]) < key(spent[j])
+	})
+}
+
+func minutes(d time.Duration) string {
+	return strconv.Itoa(int(d.Round(time.Minute) / time.Minute))
+}
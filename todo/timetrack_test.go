@@ -0,0 +1,179 @@
+package todo_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"mnishiguchi.com/todo"
+)
+
+func TestStartStop(t *testing.T) {
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	minute := func(n int) time.Time { return now.Add(time.Duration(n) * time.Minute) }
+
+	l := todo.TodoList{}
+	l.Add("Ship release")
+	l.Add("Write changelog")
+	l.Add("Pay bills")
+	l.Complete(3)
+
+	if stopped, err := l.Start(1, minute(0)); err != nil || stopped != 0 {
+		t.Fatalf("Expected nothing stopped, got %d and %v instead", stopped, err)
+	}
+
+	// Starting another item stops the running one.
+	if stopped, err := l.Start(2, minute(30)); err != nil || stopped != 1 {
+		t.Fatalf("Expected 1 stopped, got %d and %v instead", stopped, err)
+	}
+
+	if running, ok := l.Running(); !ok || running.ID != 2 {
+		t.Errorf("Expected 2 running, got %+v instead", running)
+	}
+
+	if err := l.Stop(2, minute(45)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := l.Running(); ok {
+		t.Error("Expected nothing running")
+	}
+
+	expected := []todo.Session{{Start: minute(0), End: minute(30)}}
+	if sessions := l.Items[0].Sessions; len(sessions) != 1 || sessions[0] != expected[0] {
+		t.Errorf("Expected %v, got %v instead", expected, sessions)
+	}
+
+	// Completing an item stops it.
+	l.Start(1, minute(50))
+	l.Complete(1)
+	if _, ok := l.Running(); ok {
+		t.Error("Expected completing the item to stop it")
+	}
+
+	testCases := []struct {
+		name     string
+		action   func() error
+		expected error
+	}{
+		{name: "StopStopped", action: func() error { return l.Stop(2, minute(60)) }, expected: todo.ErrNoSession},
+		{name: "StartDone", action: func() error { _, err := l.Start(3, minute(60)); return err }, expected: todo.ErrItemDone},
+		{name: "StartMissing", action: func() error { _, err := l.Start(9, minute(60)); return err }, expected: todo.ErrItemNotFound},
+		{name: "StartRunning", action: func() error {
+			l.Start(2, minute(60))
+			_, err := l.Start(2, minute(61))
+			return err
+		}, expected: todo.ErrSessionRunning},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.action(); !errors.Is(err, tc.expected) {
+				t.Errorf("Expected %q, got %q instead", tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestTimeReport(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	at := func(day, hour, minute int) time.Time { return time.Date(2026, 10, day, hour, minute, 0, 0, time.UTC) }
+
+	l := todo.TodoList{Items: []todo.TodoItem{
+		{ID: 1, Task: "Ship release", Projects: []string{"backend"}, Contexts: []string{"office"}, Sessions: []todo.Session{
+			{Start: at(8, 9, 0), End: at(8, 11, 0)},   // before both reports
+			{Start: at(8, 23, 0), End: at(9, 1, 30)},  // partly in the second report
+			{Start: at(15, 9, 0), End: at(15, 9, 45)}, // in both reports
+		}},
+		{ID: 2, Task: "Fix login bug", Projects: []string{"backend"}, Sessions: []todo.Session{
+			{Start: at(16, 10, 0)}, // still running
+		}},
+		{ID: 3, Task: "Pay bills"},
+	}}
+
+	since, err := todo.ParseSince("7d", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := l.TimeReport(since, now)
+
+	testCases := []struct {
+		name     string
+		format   string
+		expected string
+	}{
+		{
+			name:   "Text",
+			format: todo.FormatText,
+			expected: "Since 2026-10-09 12:00\n" +
+				"\nItems\n" +
+				"     2h00m  2: Fix login bug\n" +
+				"     0h45m  1: Ship release\n" +
+				"\nTags\n" +
+				"     2h45m  +backend\n" +
+				"     0h45m  @office\n" +
+				"\nTotal     2h45m\n",
+		},
+		{
+			name:   "CSV",
+			format: todo.FormatCSV,
+			expected: "Kind,ID,Name,Minutes\n" +
+				"item,2,Fix login bug,120\n" +
+				"item,1,Ship release,45\n" +
+				"tag,,+backend,165\n" +
+				"tag,,@office,45\n" +
+				"total,,,165\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := report.Write(&buf, tc.format); err != nil {
+				t.Fatal(err)
+			}
+
+			if buf.String() != tc.expected {
+				t.Errorf("Expected %q, got %q instead", tc.expected, buf.String())
+			}
+		})
+	}
+
+	// A session across the start of the report only counts from the start.
+	if total := l.TimeReport(at(9, 0, 0), now).Total; total != 4*time.Hour+15*time.Minute {
+		t.Errorf("Expected %s, got %s instead", 4*time.Hour+15*time.Minute, total)
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 30, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name     string
+		value    string
+		expected time.Time
+		err      error
+	}{
+		{name: "Days", value: "7d", expected: time.Date(2026, 10, 9, 12, 30, 0, 0, time.UTC)},
+		{name: "Weeks", value: "2w", expected: time.Date(2026, 10, 2, 12, 30, 0, 0, time.UTC)},
+		{name: "Today", value: "today", expected: time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)},
+		{name: "Date", value: "2026-10-01", expected: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "Negative", value: "-3d", err: todo.ErrInvalidSince},
+		{name: "Invalid", value: "yesterday", err: todo.ErrInvalidSince},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := todo.ParseSince(tc.value, now)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("Expected %v, got %v instead", tc.err, err)
+			}
+
+			if !res.Equal(tc.expected) {
+				t.Errorf("Expected %s, got %s instead", tc.expected, res)
+			}
+		})
+	}
+}
@@ -1,66 +1,190 @@
 package todo
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 )
 
 type TodoItem struct {
+	ID          int // stable identifier that is never reused within a list
 	Task        string
 	Done        bool
 	CreatedAt   time.Time
 	CompletedAt time.Time
+	Priority    string               // "A" to "Z", or empty for none
+	Due         time.Time            // zero when there is no due date
+	Projects    []string             // "+project" tags
+	Contexts    []string             // "@context" tags
+	Recur       string               // recurrence rule such as "30d"; see Recurrence
+	SeriesID    int                  // the ID of the first item of a recurring series
+	ParentID    int                  // the ID of the parent item, or zero at the top level
+	BlockedBy   []int                // the IDs of the items to complete before this one
+	UID         string               // identifies the item across the copies of a list
+	Modified    map[string]time.Time // when each synced field last changed
+	Sessions    []Session            // the work sessions, oldest first
 }
 
-type TodoList []TodoItem
+type TodoList struct {
+	Items   []TodoItem
+	NextID  int         // the ID given to the next item added to the list
+	Deleted []Tombstone // the deleted items, for syncing
+}
 
-// Creates a new TODO item and appends it to the list.
-func (l *TodoList) Add(task string) {
-	item := TodoItem{
-		Task:        task,
-		Done:        false,
-		CreatedAt:   time.Now(),
-		CompletedAt: time.Time{},
+// Creates a new TODO item and appends it to the list. The task may contain a
+// priority, tags and a due date in the todo.txt syntax; see ParseTask. A task
+// made only of metadata, such as "+backend @office", is blank.
+func (l *TodoList) Add(task string) error {
+	item := ParseTask(task)
+	if item.Task == "" {
+		return fmt.Errorf("%w: %q", ErrBlankTask, task)
 	}
-	*l = append(*l, item)
+
+	item.ID = l.nextID()
+	item.UID = newUID()
+	item.Done = false
+	item.CreatedAt = time.Now()
+	item.CompletedAt = time.Time{}
+
+	l.Items = append(l.Items, item)
+
+	return nil
 }
 
-// Marks an item as completed.
-func (l *TodoList) Complete(index int) error {
-	if index <= 0 || index > len(*l) {
-		return fmt.Errorf("TodoItem %d does not exist", index)
+// Marks an item as completed, stopping its work session if it is running.
+// Completing a recurring item keeps it as a record of the occurrence and adds
+// the next occurrence to the list. An item with open children cannot be
+// completed; see ForceComplete.
+func (l *TodoList) Complete(id int) error {
+	index, err := l.indexOf(id)
+	if err != nil {
+		return err
+	}
+
+	if open := l.openDescendants(id); len(open) > 0 {
+		return fmt.Errorf("%w: %d has %v", ErrOpenChildren, id, open)
 	}
 
-	// Adjust index for 0-based index.
-	(*l)[index-1].Done = true
-	(*l)[index-1].CompletedAt = time.Now()
+	l.complete(index)
 
 	return nil
 }
 
-// Removes an item from the list.
-func (l *TodoList) Delete(index int) error {
-	if index <= 0 || index > len(*l) {
-		return fmt.Errorf("TodoItem %d does not exist", index)
+// Marks an item and all of its open descendants as completed.
+func (l *TodoList) ForceComplete(id int) error {
+	if _, err := l.indexOf(id); err != nil {
+		return err
+	}
+
+	for _, child := range append(l.openDescendants(id), id) {
+		index, err := l.indexOf(child)
+		if err != nil {
+			return err
+		}
+		l.complete(index)
+	}
+
+	return nil
+}
+
+func (l *TodoList) complete(index int) {
+	wasDone := l.Items[index].Done
+
+	l.Items[index].Done = true
+	l.Items[index].CompletedAt = time.Now()
+	l.stopSession(index, l.Items[index].CompletedAt)
+
+	if !wasDone && l.Items[index].Recur != "" && !l.hasOpenSuccessor(index) {
+		l.addNextOccurrence(index)
+	}
+}
+
+// Marks a completed item as not completed again.
+func (l *TodoList) Uncomplete(id int) error {
+	index, err := l.indexOf(id)
+	if err != nil {
+		return err
+	}
+
+	l.Items[index].Done = false
+	l.Items[index].CompletedAt = time.Time{}
+
+	return nil
+}
+
+// Replaces the task of an item, keeping its identity, status, timestamps,
+// sessions and links to other items. Like Add, the task may contain a
+// priority, tags, a due date and a recurrence rule.
+func (l *TodoList) Edit(id int, task string) error {
+	index, err := l.indexOf(id)
+	if err != nil {
+		return err
+	}
+
+	old := l.Items[index]
+
+	item := ParseTask(task)
+	if item.Task == "" {
+		return fmt.Errorf("%w: %q", ErrBlankTask, task)
+	}
+
+	item.ID = old.ID
+	item.Done = old.Done
+	item.CreatedAt = old.CreatedAt
+	item.CompletedAt = old.CompletedAt
+	item.SeriesID = old.SeriesID
+	item.ParentID = old.ParentID
+	item.BlockedBy = old.BlockedBy
+	item.UID = old.UID
+	item.Modified = old.Modified
+	item.Sessions = old.Sessions
+
+	l.Items[index] = item
+
+	return nil
+}
+
+// Returns a copy of the item with the given ID.
+func (l *TodoList) Find(id int) (TodoItem, error) {
+	index, err := l.indexOf(id)
+	if err != nil {
+		return TodoItem{}, err
+	}
+
+	return l.Items[index], nil
+}
+
+// Removes an item from the list. Its children move up to its parent.
+func (l *TodoList) Delete(id int) error {
+	index, err := l.indexOf(id)
+	if err != nil {
+		return err
 	}
 
-	// Adjust index for 0-based index.
-	*l = append((*l)[:index-1], (*l)[index:]...)
+	deleted := l.Items[index]
+	l.Items = append(l.Items[:index], l.Items[index+1:]...)
+	l.unlink(deleted)
 
 	return nil
 }
 
 // Encodes the list as JSON and saves it using the provided file name.
+//
+// The data is written to a temporary file in the same directory, flushed to
+// disk and renamed over the original file, so a crash never leaves a partially
+// written list behind. Use Lock to keep other processes from saving at the same
+// time.
 func (l *TodoList) Save(filename string) error {
 	jsonifiedList, err := json.Marshal(l)
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(filename, jsonifiedList, 0644)
+	return writeFileAtomic(filename, jsonifiedList, 0644)
 }
 
 // Opens the provided file name, decodes the JSON data and parses it into a list.
@@ -78,22 +202,113 @@ func (l *TodoList) Get(filename string) error {
 		return nil
 	}
 
-	return json.Unmarshal(file, l)
+	// Older files are a plain JSON array of items without IDs.
+	if bytes.HasPrefix(bytes.TrimSpace(file), []byte("[")) {
+		if err := json.Unmarshal(file, &l.Items); err != nil {
+			return err
+		}
+	} else if err := json.Unmarshal(file, l); err != nil {
+		return err
+	}
+
+	l.migrate()
+
+	return nil
 }
 
-// Prints a formatted list, implementing the fmt.Stringer interface.
+// Prints a formatted list, implementing the fmt.Stringer interface. Children
+// are indented under their parent.
 func (l *TodoList) String() string {
 	formatted := ""
 
-	for index, item := range *l {
+	l.walkTree(func(item TodoItem, depth int) {
 		prefix := "[ ] "
 		if item.Done {
 			prefix = "[X] "
 		}
 
-		// Use one-based indexing for CLI while zero-based internally.
-		formatted += fmt.Sprintf("%s%d: %s\n", prefix, index+1, item.Task)
-	}
+		formatted += fmt.Sprintf("%s%s%d: %s\n", strings.Repeat("  ", depth), prefix, item.ID, item.Text())
+	})
 
 	return formatted
 }
+
+// Visits the items depth first, each parent followed by its children in list
+// order. Items whose parent is not in the list are visited at the top level.
+func (l *TodoList) walkTree(visit func(item TodoItem, depth int)) {
+	inList := map[int]bool{}
+	for _, item := range l.Items {
+		inList[item.ID] = true
+	}
+
+	visited := map[int]bool{}
+
+	var walk func(item TodoItem, depth int)
+	walk = func(item TodoItem, depth int) {
+		if visited[item.ID] {
+			return
+		}
+		visited[item.ID] = true
+
+		visit(item, depth)
+
+		for _, child := range l.Items {
+			if child.ParentID == item.ID && child.ID != item.ID {
+				walk(child, depth+1)
+			}
+		}
+	}
+
+	for _, item := range l.Items {
+		if item.ParentID == 0 || !inList[item.ParentID] {
+			walk(item, 0)
+		}
+	}
+
+	// Items in a cycle of parents, which only a hand-edited file can contain
+	for _, item := range l.Items {
+		walk(item, 0)
+	}
+}
+
+// Returns a new ID, which is never reused even after the item is deleted.
+func (l *TodoList) nextID() int {
+	if l.NextID < 1 {
+		l.NextID = 1
+	}
+
+	id := l.NextID
+	l.NextID++
+
+	return id
+}
+
+// Finds the position of the item with the given ID.
+func (l *TodoList) indexOf(id int) (int, error) {
+	for i, item := range l.Items {
+		if item.ID == id {
+			return i, nil
+		}
+	}
+
+	return -1, fmt.Errorf("%w: %d", ErrItemNotFound, id)
+}
+
+// Gives IDs to items loaded from files written before IDs existed. Their IDs
+// follow their positions so that the numbers users already know keep working.
+func (l *TodoList) migrate() {
+	for _, item := range l.Items {
+		if item.ID >= l.NextID {
+			l.NextID = item.ID + 1
+		}
+	}
+
+	for i := range l.Items {
+		if l.Items[i].ID == 0 {
+			l.Items[i].ID = l.nextID()
+		}
+		if l.Items[i].UID == "" {
+			l.Items[i].UID = newUID()
+		}
+	}
+}
@@ -4,8 +4,11 @@ name as the original name followed by the "_test" suffix. */
 package todo_test
 
 import (
+	"errors"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"mnishiguchi.com/todo"
 )
@@ -16,8 +19,19 @@ func TestAdd(t *testing.T) {
 	taskName := "New task"
 	list.Add(taskName)
 
-	if list[0].Task != taskName {
-		t.Errorf("Expected %q, got %q instead.", taskName, list[0].Task)
+	if list.Items[0].Task != taskName {
+		t.Errorf("Expected %q, got %q instead.", taskName, list.Items[0].Task)
+	}
+
+	// A task made only of metadata is blank.
+	for _, task := range []string{"+foo @home", "(A) due:2026-11-01", " "} {
+		if err := list.Add(task); !errors.Is(err, todo.ErrBlankTask) {
+			t.Errorf("Expected %q for %q, got %v instead", todo.ErrBlankTask, task, err)
+		}
+	}
+
+	if len(list.Items) != 1 {
+		t.Errorf("Expected no blank items, got %+v instead", list.Items)
 	}
 }
 
@@ -27,21 +41,66 @@ func TestComplete(t *testing.T) {
 	taskName := "New task"
 	list.Add(taskName)
 
-	if list[0].Task != taskName {
-		t.Errorf("Expected %q, got %q instead.", taskName, list[0].Task)
+	if list.Items[0].Task != taskName {
+		t.Errorf("Expected %q, got %q instead.", taskName, list.Items[0].Task)
 	}
 
-	if list[0].Done {
+	if list.Items[0].Done {
 		t.Errorf("New task should not be completed")
 	}
 
 	list.Complete(1)
 
-	if !list[0].Done {
+	if !list.Items[0].Done {
 		t.Errorf("New task should be completed")
 	}
 }
 
+func TestUncomplete(t *testing.T) {
+	list := todo.TodoList{}
+	list.Add("New task")
+	list.Complete(1)
+
+	if err := list.Uncomplete(1); err != nil {
+		t.Fatal(err)
+	}
+
+	if list.Items[0].Done || !list.Items[0].CompletedAt.IsZero() {
+		t.Errorf("Task should not be completed anymore")
+	}
+}
+
+func TestEdit(t *testing.T) {
+	list := todo.TodoList{}
+	list.Add("New task +old")
+	list.Complete(1)
+
+	if err := list.Edit(1, "(A) Edited task +new"); err != nil {
+		t.Fatal(err)
+	}
+
+	item, err := list.Find(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if item.Task != "Edited task" || item.Priority != "A" || item.Projects[0] != "new" {
+		t.Errorf("Expected the edited task, got %+v instead", item)
+	}
+
+	if !item.Done || item.CreatedAt.IsZero() {
+		t.Errorf("Expected the status and timestamps to be kept, got %+v instead", item)
+	}
+
+	if err := list.Edit(1, "+new"); !errors.Is(err, todo.ErrBlankTask) {
+		t.Errorf("Expected %q, got %q instead", todo.ErrBlankTask, err)
+	}
+
+	if err := list.Edit(2, "Missing"); !errors.Is(err, todo.ErrItemNotFound) {
+		t.Errorf("Expected %q, got %q instead", todo.ErrItemNotFound, err)
+	}
+}
+
 func TestDelete(t *testing.T) {
 	list := todo.TodoList{}
 
@@ -55,18 +114,18 @@ func TestDelete(t *testing.T) {
 		list.Add(v)
 	}
 
-	if list[0].Task != tasks[0] {
-		t.Errorf("Expected %q, got %q instead.", tasks[0], list[0].Task)
+	if list.Items[0].Task != tasks[0] {
+		t.Errorf("Expected %q, got %q instead.", tasks[0], list.Items[0].Task)
 	}
 
 	list.Delete(2)
 
-	if len(list) != 2 {
-		t.Errorf("Expected list length %d, got %q instead.", 2, len(list))
+	if len(list.Items) != 2 {
+		t.Errorf("Expected list length %d, got %d instead.", 2, len(list.Items))
 	}
 
-	if list[1].Task != tasks[2] {
-		t.Errorf("Expected %q, got %q instead.", tasks[2], list[1].Task)
+	if list.Items[1].Task != tasks[2] {
+		t.Errorf("Expected %q, got %q instead.", tasks[2], list.Items[1].Task)
 	}
 }
 
@@ -77,8 +136,8 @@ func TestSaveGet(t *testing.T) {
 	taskName := "New task"
 	list1.Add(taskName)
 
-	if list1[0].Task != taskName {
-		t.Errorf("Expected %q, got %q instead.", taskName, list1[0].Task)
+	if list1.Items[0].Task != taskName {
+		t.Errorf("Expected %q, got %q instead.", taskName, list1.Items[0].Task)
 	}
 
 	// Create a temporary file.
@@ -95,6 +154,144 @@ func TestSaveGet(t *testing.T) {
 
 	// Get a list from a file through a new list instance.
 	if err := list2.Get(tmp.Name()); err != nil {
-		t.Fatalf("Task %q should match %q task.", list1[0].Task, list2[0].Task)
+		t.Fatalf("Task %q should match %q task.", list1.Items[0].Task, list2.Items[0].Task)
+	}
+}
+
+func TestStableIDs(t *testing.T) {
+	list := todo.TodoList{}
+
+	for _, v := range []string{"New task 1", "New task 2", "New task 3"} {
+		list.Add(v)
+	}
+
+	if err := list.Delete(2); err != nil {
+		t.Fatal(err)
+	}
+
+	// Deleting an item does not shift the IDs of the following items.
+	if err := list.Complete(3); err != nil {
+		t.Fatal(err)
+	}
+
+	if !list.Items[1].Done || list.Items[1].Task != "New task 3" {
+		t.Errorf("Expected %q to be completed", "New task 3")
+	}
+
+	if err := list.Complete(2); !errors.Is(err, todo.ErrItemNotFound) {
+		t.Errorf("Expected error %q, got %v instead.", todo.ErrItemNotFound, err)
+	}
+
+	// IDs are never reused, even when the last item is deleted.
+	if err := list.Delete(3); err != nil {
+		t.Fatal(err)
+	}
+
+	list.Add("New task 4")
+
+	if id := list.Items[len(list.Items)-1].ID; id != 4 {
+		t.Errorf("Expected ID %d, got %d instead.", 4, id)
+	}
+}
+
+func TestGetMigratesLegacyFile(t *testing.T) {
+	tmp, err := os.CreateTemp("", "")
+	if err != nil {
+		t.Fatalf("Error creating temp file: %s", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	// A file written before IDs existed.
+	legacy := `[{"Task":"Old task 1","Done":false},{"Task":"Old task 2","Done":true}]`
+	if _, err := tmp.WriteString(legacy); err != nil {
+		t.Fatal(err)
+	}
+	tmp.Close()
+
+	list := todo.TodoList{}
+	if err := list.Get(tmp.Name()); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, item := range list.Items {
+		if item.ID != i+1 {
+			t.Errorf("Expected ID %d for %q, got %d instead.", i+1, item.Task, item.ID)
+		}
+	}
+
+	list.Add("New task")
+	if id := list.Items[2].ID; id != 3 {
+		t.Errorf("Expected ID %d, got %d instead.", 3, id)
+	}
+
+	// The IDs are stored in the file once it is saved.
+	if err := list.Save(tmp.Name()); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded := todo.TodoList{}
+	if err := reloaded.Get(tmp.Name()); err != nil {
+		t.Fatal(err)
+	}
+
+	if reloaded.NextID != 4 || reloaded.Items[2].ID != 3 {
+		t.Errorf("Expected IDs to be saved, got %+v instead.", reloaded)
+	}
+}
+
+func TestLock(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "todo.json")
+
+	lock, err := todo.Lock(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	acquired := make(chan *todo.FileLock)
+	go func() {
+		lock2, err := todo.Lock(filename)
+		if err != nil {
+			t.Error(err)
+		}
+		acquired <- lock2
+	}()
+
+	// The second lock must wait for the first one to be released.
+	select {
+	case <-acquired:
+		t.Fatal("Expected the second lock to wait")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if err := lock.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	lock2 := <-acquired
+	if err := lock2.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSaveLeavesNoTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "todo.json")
+
+	list := todo.TodoList{}
+	list.Add("New task")
+
+	for i := 0; i < 3; i++ {
+		if err := list.Save(filename); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 1 || entries[0].Name() != "todo.json" {
+		t.Errorf("Expected only todo.json, got %v instead.", entries)
 	}
 }
@@ -0,0 +1,541 @@
+package todo
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The formats that lists can be exported to and imported from.
+const (
+	FormatTodoTxt  = "todotxt"
+	FormatCSV      = "csv"
+	FormatMarkdown = "markdown"
+	FormatJSON     = "json"
+)
+
+// The columns of the CSV format, in order.
+var csvHeader = []string{"id", "done", "priority", "task", "projects", "contexts", "due", "recur", "created", "completed"}
+
+var (
+	todoTxtDatePattern = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2})(\s+|$)`)
+	checklistPattern   = regexp.MustCompile(`^(\s*)[-*+]\s+\[([ xX])\]\s*(.*)$`)
+)
+
+// The outcome of an import.
+type ImportResult struct {
+	Added   []TodoItem // the items added to the list, with their new IDs
+	Skipped int        // the number of duplicates that were not added
+}
+
+// Writes the items in the given format.
+func (l *TodoList) Export(w io.Writer, format string) error {
+	switch format {
+	case FormatTodoTxt:
+		return l.exportTodoTxt(w)
+	case FormatCSV:
+		return l.exportCSV(w)
+	case FormatMarkdown:
+		return l.exportMarkdown(w)
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(l)
+	}
+
+	return fmt.Errorf("%w: %q", ErrInvalidFormat, format)
+}
+
+// Guesses the format of the data from the extension of the file name, or from
+// the content when the extension is not known.
+func DetectFormat(filename string, data []byte) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return FormatCSV
+	case ".md", ".markdown":
+		return FormatMarkdown
+	case ".json":
+		return FormatJSON
+	case ".txt":
+		return FormatTodoTxt
+	}
+
+	trimmed := bytes.TrimSpace(data)
+	if bytes.HasPrefix(trimmed, []byte("{")) {
+		return FormatJSON
+	}
+
+	// An array of items, maybe empty or indented, unlike a todo.txt line
+	// such as "[work] Ship release".
+	if bytes.HasPrefix(trimmed, []byte("[")) {
+		rest := bytes.TrimSpace(trimmed[1:])
+		if bytes.HasPrefix(rest, []byte("{")) || bytes.HasPrefix(rest, []byte("]")) {
+			return FormatJSON
+		}
+	}
+
+	firstLine := string(trimmed)
+	if i := strings.IndexByte(firstLine, '\n'); i >= 0 {
+		firstLine = firstLine[:i]
+	}
+
+	switch {
+	case checklistPattern.MatchString(firstLine) || strings.HasPrefix(firstLine, "#"):
+		return FormatMarkdown
+	case strings.Contains(firstLine, ",") && strings.Contains(strings.ToLower(firstLine), "task"):
+		return FormatCSV
+	}
+
+	return FormatTodoTxt
+}
+
+// Reads items in the given format. The IDs of the items are the ones in the
+// data, if any, and are only used to link children and blockers.
+func ParseItems(r io.Reader, format string) ([]TodoItem, error) {
+	switch format {
+	case FormatTodoTxt:
+		return parseTodoTxt(r)
+	case FormatCSV:
+		return parseCSV(r)
+	case FormatMarkdown:
+		return parseMarkdown(r)
+	case FormatJSON:
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+
+		list := &TodoList{}
+		if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+			err = json.Unmarshal(data, &list.Items)
+		} else {
+			err = json.Unmarshal(data, list)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+		}
+
+		return list.Items, nil
+	}
+
+	return nil, fmt.Errorf("%w: %q", ErrInvalidFormat, format)
+}
+
+// Adds the items to the list with new IDs, skipping the ones with a blank task
+// and the ones with the same task and the same creation date as an item in the
+// list or an item imported before them. Items without a creation date, such as
+// the ones from a Markdown checklist, count as created today, as they are once
+// imported. Links between the imported items are kept.
+func (l *TodoList) Import(items []TodoItem) ImportResult {
+	result := ImportResult{}
+	now := time.Now()
+
+	newItems := []TodoItem{}
+	for _, item := range items {
+		if strings.TrimSpace(item.Task) == "" {
+			result.Skipped++
+			continue
+		}
+
+		// The series of the data are not the ones of the list.
+		unlinked := item
+		unlinked.SeriesID = 0
+		unlinked.Recur = ""
+
+		if hasDuplicate(l.Items, unlinked, now) || hasDuplicate(newItems, item, now) {
+			result.Skipped++
+			continue
+		}
+		newItems = append(newItems, item)
+	}
+
+	result.Added = l.insert(newItems)
+
+	return result
+}
+
+// Appends items from another list with new IDs and returns them. Links
+// between the items are updated to the new IDs; links to other items are
+// dropped. Missing timestamps are set to now.
+func (l *TodoList) insert(items []TodoItem) []TodoItem {
+	newIDs := map[int]int{}
+	now := time.Now()
+	start := len(l.Items)
+
+	for _, item := range items {
+		oldID := item.ID
+		item.ID = l.nextID()
+		if oldID != 0 {
+			newIDs[oldID] = item.ID
+		}
+
+		// The item is new to the list, even when it comes from another one.
+		item.UID = newUID()
+		item.Modified = nil
+
+		if item.CreatedAt.IsZero() {
+			item.CreatedAt = now
+		}
+		if item.Done && item.CompletedAt.IsZero() {
+			item.CompletedAt = now
+		}
+
+		l.Items = append(l.Items, item)
+	}
+
+	for i := start; i < len(l.Items); i++ {
+		item := &l.Items[i]
+
+		item.ParentID = newIDs[item.ParentID]
+		item.SeriesID = newIDs[item.SeriesID]
+
+		blockers := []int{}
+		for _, id := range item.BlockedBy {
+			if newID, ok := newIDs[id]; ok {
+				blockers = append(blockers, newID)
+			}
+		}
+		item.BlockedBy = nil
+		if len(blockers) > 0 {
+			item.BlockedBy = blockers
+		}
+	}
+
+	return append([]TodoItem(nil), l.Items[start:]...)
+}
+
+// Reports whether the items have one with the same task created on the same
+// day, where a missing creation date counts as now. The occurrences of a
+// recurring item are not duplicates of each other; the formats without series
+// links tell them by their recurrence rule.
+func hasDuplicate(items []TodoItem, item TodoItem, now time.Time) bool {
+	created := func(i TodoItem) time.Time {
+		if i.CreatedAt.IsZero() {
+			return now
+		}
+		return i.CreatedAt
+	}
+
+	for _, other := range items {
+		if other.Task != item.Task {
+			continue
+		}
+
+		if item.SeriesID != 0 && other.SeriesID == item.SeriesID || item.Recur != "" && other.Recur == item.Recur {
+			continue
+		}
+
+		if compareDays(created(other), created(item)) == 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Writes one item per line in the todo.txt format:
+//
+//	(A) 2026-10-01 Ship release +backend due:2026-11-01
+//	x 2026-10-16 2026-10-01 Pay bills pri:B
+func (l *TodoList) exportTodoTxt(w io.Writer) error {
+	for _, item := range l.Items {
+		parts := []string{}
+
+		text := item.Text()
+		if item.Done {
+			parts = append(parts, "x")
+			if !item.CompletedAt.IsZero() {
+				parts = append(parts, item.CompletedAt.Format(DateLayout))
+			}
+
+			// todo.txt keeps the priority of a completed item as a tag.
+			if item.Priority != "" {
+				text = strings.TrimPrefix(text, "("+item.Priority+") ") + " pri:" + item.Priority
+			}
+		} else if item.Priority != "" {
+			parts = append(parts, "("+item.Priority+")")
+			text = strings.TrimPrefix(text, "("+item.Priority+") ")
+		}
+
+		if !item.CreatedAt.IsZero() {
+			parts = append(parts, item.CreatedAt.Format(DateLayout))
+		}
+		parts = append(parts, text)
+
+		if _, err := fmt.Fprintln(w, strings.Join(parts, " ")); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func parseTodoTxt(r io.Reader) ([]TodoItem, error) {
+	items := []TodoItem{}
+
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" {
+			continue
+		}
+
+		item := TodoItem{}
+
+		if strings.HasPrefix(line, "x ") {
+			item.Done = true
+			line = strings.TrimSpace(line[2:])
+
+			if date, rest, ok := cutDate(line); ok {
+				item.CompletedAt = date
+				line = rest
+			}
+		}
+
+		priority := ""
+		if m := priorityPattern.FindStringSubmatch(line); m != nil {
+			priority = m[1]
+			line = line[len(m[0]):]
+		}
+
+		if date, rest, ok := cutDate(line); ok {
+			item.CreatedAt = date
+			line = rest
+		}
+
+		parsed := ParseTask(line)
+		parsed.Done = item.Done
+		parsed.CreatedAt = item.CreatedAt
+		parsed.CompletedAt = item.CompletedAt
+		if priority != "" {
+			parsed.Priority = priority
+		}
+
+		// A completed item keeps its priority as a "pri:" tag.
+		words := []string{}
+		for _, word := range strings.Fields(parsed.Task) {
+			if strings.HasPrefix(word, "pri:") && len(word) == 5 && word[4] >= 'A' && word[4] <= 'Z' {
+				parsed.Priority = word[4:]
+				continue
+			}
+			words = append(words, word)
+		}
+		parsed.Task = strings.Join(words, " ")
+
+		// A line made only of dates and tags has no task.
+		if parsed.Task == "" {
+			continue
+		}
+
+		items = append(items, parsed)
+	}
+
+	return items, s.Err()
+}
+
+// Removes a leading date from the text.
+func cutDate(text string) (time.Time, string, bool) {
+	m := todoTxtDatePattern.FindStringSubmatch(text)
+	if m == nil {
+		return time.Time{}, text, false
+	}
+
+	date, err := time.ParseInLocation(DateLayout, m[1], time.Local)
+	if err != nil {
+		return time.Time{}, text, false
+	}
+
+	return date, text[len(m[0]):], true
+}
+
+func (l *TodoList) exportCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, item := range l.Items {
+		record := []string{
+			strconv.Itoa(item.ID),
+			strconv.FormatBool(item.Done),
+			item.Priority,
+			item.Task,
+			strings.Join(item.Projects, " "),
+			strings.Join(item.Contexts, " "),
+			formatTime(item.Due, DateLayout),
+			item.Recur,
+			formatTime(item.CreatedAt, time.RFC3339),
+			formatTime(item.CompletedAt, time.RFC3339),
+		}
+
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// Reads the CSV format written by Export. Columns are found by their names in
+// the header, so they may come in any order and unknown ones are ignored.
+func parseCSV(r io.Reader) ([]TodoItem, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+
+	if len(records) == 0 {
+		return []TodoItem{}, nil
+	}
+
+	columns := map[string]int{}
+	for i, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	if _, ok := columns["task"]; !ok {
+		return nil, fmt.Errorf("%w: no task column", ErrInvalidImport)
+	}
+
+	items := []TodoItem{}
+	for n, record := range records[1:] {
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		item := TodoItem{
+			Task:     field("task"),
+			Priority: field("priority"),
+			Projects: strings.Fields(field("projects")),
+			Contexts: strings.Fields(field("contexts")),
+			Recur:    field("recur"),
+		}
+
+		if item.Task == "" {
+			continue
+		}
+
+		var err error
+		if id := field("id"); id != "" {
+			item.ID, err = strconv.Atoi(id)
+		}
+		if done := field("done"); err == nil && done != "" {
+			item.Done, err = strconv.ParseBool(done)
+		}
+		if err == nil {
+			item.Due, err = parseTime(field("due"), DateLayout)
+		}
+		if err == nil {
+			item.CreatedAt, err = parseTime(field("created"), time.RFC3339)
+		}
+		if err == nil {
+			item.CompletedAt, err = parseTime(field("completed"), time.RFC3339)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidImport, n+2, err)
+		}
+
+		if len(item.Projects) == 0 {
+			item.Projects = nil
+		}
+		if len(item.Contexts) == 0 {
+			item.Contexts = nil
+		}
+
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// Writes the list as a Markdown checklist with children indented under their
+// parents.
+func (l *TodoList) exportMarkdown(w io.Writer) error {
+	var err error
+
+	l.walkTree(func(item TodoItem, depth int) {
+		box := "[ ]"
+		if item.Done {
+			box = "[x]"
+		}
+
+		if err == nil {
+			_, err = fmt.Fprintf(w, "%s- %s %s\n", strings.Repeat("  ", depth), box, item.Text())
+		}
+	})
+
+	return err
+}
+
+// Reads the items of a Markdown checklist, ignoring every other line. An item
+// indented under another one becomes its child.
+func parseMarkdown(r io.Reader) ([]TodoItem, error) {
+	items := []TodoItem{}
+
+	// The IDs and indentations of the items that may be parents of the next one
+	type level struct {
+		id     int
+		indent int
+	}
+	parents := []level{}
+
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		m := checklistPattern.FindStringSubmatch(s.Text())
+		if m == nil || strings.TrimSpace(m[3]) == "" {
+			continue
+		}
+
+		indent := len(strings.ReplaceAll(m[1], "\t", "    "))
+
+		item := ParseTask(m[3])
+		if item.Task == "" {
+			continue
+		}
+		item.ID = len(items) + 1
+		item.Done = m[2] != " "
+
+		for len(parents) > 0 && parents[len(parents)-1].indent >= indent {
+			parents = parents[:len(parents)-1]
+		}
+		if len(parents) > 0 {
+			item.ParentID = parents[len(parents)-1].id
+		}
+		parents = append(parents, level{id: item.ID, indent: indent})
+
+		items = append(items, item)
+	}
+
+	return items, s.Err()
+}
+
+// Formats the time with the layout, or returns an empty string for zero.
+func formatTime(t time.Time, layout string) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.Format(layout)
+}
+
+// Parses the time with the layout, or returns zero for an empty string.
+func parseTime(value, layout string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	return time.ParseInLocation(layout, value, time.Local)
+}
@@ -0,0 +1,220 @@
+package todo_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"mnishiguchi.com/todo"
+)
+
+func TestExportImportRoundTrip(t *testing.T) {
+	list := todo.TodoList{}
+	list.Add("(A) Ship release +backend @office due:2026-11-01")
+	list.Add("Write changelog")
+	list.Add("Pay bills rec:+1m")
+	list.SetParent(2, 1)
+	list.Items[2].Priority = "B"
+	list.Complete(3)
+
+	for _, format := range []string{todo.FormatTodoTxt, todo.FormatCSV, todo.FormatMarkdown, todo.FormatJSON} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := list.Export(&buf, format); err != nil {
+				t.Fatal(err)
+			}
+
+			items, err := todo.ParseItems(&buf, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			imported := todo.TodoList{}
+			imported.Import(items)
+
+			// Only JSON and Markdown keep the subtasks, so compare the items
+			// without them.
+			expected, res := flatten(&list), flatten(&imported)
+			if res != expected {
+				t.Errorf("Expected %q, got %q instead", expected, res)
+			}
+		})
+	}
+}
+
+// Lists the IDs, done states and texts of the items, one per line.
+func flatten(l *todo.TodoList) string {
+	flat := ""
+	for _, item := range l.Items {
+		flat += fmt.Sprintf("%d %t %s\n", item.ID, item.Done, item.Text())
+	}
+
+	return flat
+}
+
+func TestExportTodoTxt(t *testing.T) {
+	created := time.Date(2026, 10, 1, 9, 0, 0, 0, time.Local)
+	list := todo.TodoList{Items: []todo.TodoItem{
+		{ID: 1, Task: "Ship release", Priority: "A", Projects: []string{"backend"}, CreatedAt: created},
+		{ID: 2, Task: "Pay bills", Priority: "B", Done: true, CreatedAt: created,
+			CompletedAt: time.Date(2026, 10, 16, 9, 0, 0, 0, time.Local)},
+	}}
+
+	expected := "(A) 2026-10-01 Ship release +backend\n" +
+		"x 2026-10-16 2026-10-01 Pay bills pri:B\n"
+
+	var buf bytes.Buffer
+	if err := list.Export(&buf, todo.FormatTodoTxt); err != nil {
+		t.Fatal(err)
+	}
+
+	if buf.String() != expected {
+		t.Errorf("Expected %q, got %q instead", expected, buf.String())
+	}
+
+	if err := list.Export(&buf, "yaml"); !errors.Is(err, todo.ErrInvalidFormat) {
+		t.Errorf("Expected %q, got %q instead", todo.ErrInvalidFormat, err)
+	}
+}
+
+func TestParseMarkdown(t *testing.T) {
+	data := "# Release\n\n" +
+		"Some notes\n" +
+		"- [ ] Ship release +backend\n" +
+		"  - [x] Write changelog\n" +
+		"  * [ ] Tag the commit\n" +
+		"- [X] Book the room @office\n"
+
+	items, err := todo.ParseItems(strings.NewReader(data), todo.FormatMarkdown)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list := todo.TodoList{}
+	list.Import(items)
+
+	expected := "[ ] 1: Ship release +backend\n" +
+		"  [X] 2: Write changelog\n" +
+		"  [ ] 3: Tag the commit\n" +
+		"[X] 4: Book the room @office\n"
+
+	if res := list.String(); res != expected {
+		t.Errorf("Expected %q, got %q instead", expected, res)
+	}
+}
+
+func TestImportSkipsBlankTasks(t *testing.T) {
+	testCases := []struct {
+		name   string
+		format string
+		data   string
+	}{
+		{name: "TodoTxt", format: todo.FormatTodoTxt, data: "x 2026-01-01\n(A) +backend @office\nShip release\n"},
+		{name: "Markdown", format: todo.FormatMarkdown, data: "- [ ] +backend @office\n- [ ] Ship release\n"},
+		{name: "JSON", format: todo.FormatJSON, data: `[{"Task": " "}, {"Task": "Ship release"}]`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			items, err := todo.ParseItems(strings.NewReader(tc.data), tc.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			list := todo.TodoList{}
+			list.Import(items)
+
+			expected := "[ ] 1: Ship release\n"
+			if res := list.String(); res != expected {
+				t.Errorf("Expected %q, got %q instead", expected, res)
+			}
+		})
+	}
+}
+
+func TestImportSkipsDuplicates(t *testing.T) {
+	list := todo.TodoList{}
+	list.Add("Ship release")
+
+	today := time.Now()
+	items := []todo.TodoItem{
+		{ID: 1, Task: "Ship release", CreatedAt: today},
+		{ID: 2, Task: "Ship release", CreatedAt: today.AddDate(0, 0, -3)},
+		{ID: 3, Task: "Ship release"},
+		{ID: 4, Task: "Write docs", BlockedBy: []int{2, 1}},
+	}
+
+	result := list.Import(items)
+
+	if result.Skipped != 2 || len(result.Added) != 2 {
+		t.Fatalf("Expected 2 added and 2 skipped, got %d added and %d skipped instead", len(result.Added), result.Skipped)
+	}
+
+	// The link to the imported item is kept with its new ID; the one to the
+	// skipped item is dropped.
+	docs := result.Added[1]
+	if docs.ID != 3 || len(docs.BlockedBy) != 1 || docs.BlockedBy[0] != 2 {
+		t.Errorf("Expected item 3 blocked by 2, got %+v instead", docs)
+	}
+}
+
+func TestImportSkipsDuplicatesWithinData(t *testing.T) {
+	list := todo.TodoList{}
+	list.Add("Water plants")
+	list.Items[0].CreatedAt = time.Now().AddDate(0, 0, -7)
+
+	items, err := todo.ParseItems(strings.NewReader("- [ ] Call mom\n- [ ] Call mom\n- [ ] Water plants\n"), todo.FormatMarkdown)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An undated item is not a duplicate of an item created on another day.
+	result := list.Import(items)
+	if result.Skipped != 1 || len(result.Added) != 2 {
+		t.Fatalf("Expected 2 added and 1 skipped, got %d added and %d skipped instead", len(result.Added), result.Skipped)
+	}
+
+	// The occurrences of a recurring item are kept.
+	today := time.Now()
+	occurrences := []todo.TodoItem{
+		{ID: 1, Task: "Rotate certs", SeriesID: 1, Done: true, CreatedAt: today},
+		{ID: 2, Task: "Rotate certs", SeriesID: 1, CreatedAt: today},
+	}
+	if result := list.Import(occurrences); result.Skipped != 0 {
+		t.Errorf("Expected no duplicates, got %d instead", result.Skipped)
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	testCases := []struct {
+		name     string
+		filename string
+		data     string
+		expected string
+	}{
+		{name: "CSVExtension", filename: "tasks.CSV", expected: todo.FormatCSV},
+		{name: "MarkdownExtension", filename: "notes.md", expected: todo.FormatMarkdown},
+		{name: "JSONExtension", filename: ".todo.json", expected: todo.FormatJSON},
+		{name: "TextExtension", filename: "todo.txt", data: "- [ ] Looks like markdown", expected: todo.FormatTodoTxt},
+		{name: "JSONContent", filename: "-", data: "  {\"Items\": []}", expected: todo.FormatJSON},
+		{name: "LegacyJSONContent", filename: "-", data: "[{\"Task\": \"a\"}]", expected: todo.FormatJSON},
+		{name: "IndentedJSONContent", filename: "-", data: "[\n  {\n    \"Task\": \"a\"\n  }\n]", expected: todo.FormatJSON},
+		{name: "EmptyJSONContent", filename: "-", data: "[]", expected: todo.FormatJSON},
+		{name: "BracketTodoTxtContent", filename: "-", data: "[work] Ship release\n", expected: todo.FormatTodoTxt},
+		{name: "MarkdownContent", filename: "-", data: "- [ ] Task\n", expected: todo.FormatMarkdown},
+		{name: "MarkdownHeading", filename: "-", data: "# Tasks\n- [ ] Task\n", expected: todo.FormatMarkdown},
+		{name: "CSVContent", filename: "-", data: "Task,Due\nShip,2026-11-01\n", expected: todo.FormatCSV},
+		{name: "TodoTxtContent", filename: "-", data: "(A) 2026-10-01 Ship release\n", expected: todo.FormatTodoTxt},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if res := todo.DetectFormat(tc.filename, []byte(tc.data)); res != tc.expected {
+				t.Errorf("Expected %q, got %q instead", tc.expected, res)
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package todo
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// The name of the list used when none is given.
+const DefaultListName = "default"
+
+// The default directory of a workspace.
+const DefaultWorkspaceDir = ".todo"
+
+var listNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// A directory of named lists, such as "work", "home" and "release-2026.11".
+// Each list is kept in its own file, named after the list, using the same
+// kind of store for every list.
+type Workspace struct {
+	Dir  string
+	Kind string // the kind of store; see NewStore
+}
+
+// The number of open and completed items of a list.
+type ListSummary struct {
+	Name string
+	Open int
+	Done int
+}
+
+// Returns the name of the file that keeps the list.
+func (w *Workspace) Filename(name string) (string, error) {
+	if !listNamePattern.MatchString(name) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidListName, name)
+	}
+
+	return filepath.Join(w.Dir, name+w.ext()), nil
+}
+
+// Returns the store of the list and the name of its file, creating the
+// workspace directory if needed. The list itself is created on the first save.
+func (w *Workspace) Open(name string) (Store, string, error) {
+	filename, err := w.Filename(name)
+	if err != nil {
+		return nil, "", err
+	}
+
+	store, err := NewStore(w.Kind, filename)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := os.MkdirAll(w.Dir, 0755); err != nil {
+		return nil, "", err
+	}
+
+	return store, filename, nil
+}
+
+// Returns the names of the lists in the workspace in alphabetical order.
+func (w *Workspace) Names() ([]string, error) {
+	entries, err := os.ReadDir(w.Dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []string{}, nil
+		}
+
+		return nil, err
+	}
+
+	names := []string{}
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), w.ext())
+		if entry.IsDir() || name == entry.Name() || !listNamePattern.MatchString(name) {
+			continue
+		}
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// Returns the number of open and completed items of every list.
+func (w *Workspace) Summaries() ([]ListSummary, error) {
+	names, err := w.Names()
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := []ListSummary{}
+	for _, name := range names {
+		filename, err := w.Filename(name)
+		if err != nil {
+			return nil, err
+		}
+
+		store, err := NewStore(w.Kind, filename)
+		if err != nil {
+			return nil, err
+		}
+
+		list := &TodoList{}
+		if err := store.Load(list); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		summary := ListSummary{Name: name}
+		for _, item := range list.Items {
+			if item.Done {
+				summary.Done++
+			} else {
+				summary.Open++
+			}
+		}
+		summaries = append(summaries, summary)
+	}
+
+	return summaries, nil
+}
+
+// Moves a list file written before workspaces existed, along with its journal
+// and archive, into the workspace as the default list. Nothing happens when there is no
+// such file or when the default list already exists.
+//
+// The locks of both files are held while moving, so that a change made to
+// the legacy file by another process is not lost.
+func (w *Workspace) Migrate(legacyFilename string) error {
+	if _, err := os.Stat(legacyFilename); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+
+		return err
+	}
+
+	filename, err := w.Filename(DefaultListName)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(w.Dir, 0755); err != nil {
+		return err
+	}
+
+	legacyLock, err := Lock(legacyFilename)
+	if err != nil {
+		return err
+	}
+	defer legacyLock.Unlock()
+
+	lock, err := Lock(filename)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	// Another process may have migrated the file while waiting for the locks.
+	if _, err := os.Stat(legacyFilename); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	if _, err := os.Stat(filename); err == nil {
+		return nil
+	}
+
+	if err := os.Rename(legacyFilename, filename); err != nil {
+		return err
+	}
+
+	for _, related := range []func(string) string{JournalFilename, ArchiveFilename} {
+		err = os.Rename(related(legacyFilename), related(filename))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Returns the extension of the list files, such as ".json".
+func (w *Workspace) ext() string {
+	return filepath.Ext(DefaultFilename(w.Kind))
+}
+
+// Moves the item and its descendants from one list to another, where they get
+// new IDs. Links between the moved items are kept; links to items that stay
+// behind are dropped. Returns the moved items as added to the other list.
+func MoveItem(from, to *TodoList, id int) ([]TodoItem, error) {
+	if _, err := from.indexOf(id); err != nil {
+		return nil, err
+	}
+
+	ids := append([]int{id}, from.descendants(id)...)
+
+	items := []TodoItem{}
+	for _, i := range ids {
+		moved, err := from.Find(i)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, moved)
+	}
+
+	// The moved item becomes a top-level item of the other list.
+	items[0].ParentID = 0
+
+	for _, i := range ids {
+		if err := from.Delete(i); err != nil {
+			return nil, err
+		}
+	}
+
+	return to.insert(items), nil
+}
+
+// Returns the IDs of all descendants of the item.
+func (l *TodoList) descendants(id int) []int {
+	ids := []int{}
+
+	for _, item := range l.Items {
+		if item.ParentID == id && item.ID != id {
+			ids = append(ids, item.ID)
+			ids = append(ids, l.descendants(item.ID)...)
+		}
+	}
+
+	return ids
+}
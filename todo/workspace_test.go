@@ -0,0 +1,202 @@
+package todo_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"mnishiguchi.com/todo"
+)
+
+func TestWorkspace(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "lists")
+	ws := &todo.Workspace{Dir: dir, Kind: todo.StoreJSON}
+
+	// An empty workspace has no lists, and the directory is not created.
+	names, err := ws.Names()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 0 {
+		t.Errorf("Expected no lists, got %v instead", names)
+	}
+
+	for _, name := range []string{"work", "release-2026.11", "home"} {
+		store, _, err := ws.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		list := todo.TodoList{}
+		list.Add("Task 1")
+		list.Add("Task 2")
+		if name == "home" {
+			list.Complete(1)
+		}
+
+		if err := store.Save(&list); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Files that are not lists are ignored.
+	os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0644)
+	os.WriteFile(filepath.Join(dir, "work.json.journal"), nil, 0644)
+
+	summaries, err := ws.Summaries()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []todo.ListSummary{
+		{Name: "home", Open: 1, Done: 1},
+		{Name: "release-2026.11", Open: 2},
+		{Name: "work", Open: 2},
+	}
+
+	if !reflect.DeepEqual(summaries, expected) {
+		t.Errorf("Expected %+v, got %+v instead", expected, summaries)
+	}
+
+	for _, name := range []string{"", "../work", ".hidden", "a/b"} {
+		if _, err := ws.Filename(name); !errors.Is(err, todo.ErrInvalidListName) {
+			t.Errorf("Expected %q for %q, got %v instead", todo.ErrInvalidListName, name, err)
+		}
+	}
+}
+
+func TestWorkspaceMigrate(t *testing.T) {
+	tmp := t.TempDir()
+	legacy := filepath.Join(tmp, ".todo.json")
+
+	list := todo.TodoList{}
+	list.Add("Old task")
+	if err := list.Save(legacy); err != nil {
+		t.Fatal(err)
+	}
+
+	ws := &todo.Workspace{Dir: filepath.Join(tmp, ".todo"), Kind: todo.StoreJSON}
+	if err := ws.Migrate(legacy); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(legacy); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected the legacy file to be moved, got %v instead", err)
+	}
+
+	store, _, err := ws.Open(todo.DefaultListName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	migrated := todo.TodoList{}
+	if err := store.Load(&migrated); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(migrated.Items) != 1 || migrated.Items[0].Task != "Old task" {
+		t.Errorf("Expected the old task, got %+v instead", migrated.Items)
+	}
+
+	// Migrating again without a legacy file does nothing.
+	if err := ws.Migrate(legacy); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWorkspaceMigrateLocked(t *testing.T) {
+	tmp := t.TempDir()
+	legacy := filepath.Join(tmp, ".todo.json")
+
+	list := todo.TodoList{}
+	list.Add("Old task")
+	if err := list.Save(legacy); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another process is changing the legacy file.
+	lock, err := todo.Lock(legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ws := &todo.Workspace{Dir: filepath.Join(tmp, ".todo"), Kind: todo.StoreJSON}
+	done := make(chan error)
+	go func() { done <- ws.Migrate(legacy) }()
+
+	select {
+	case err := <-done:
+		t.Fatalf("Expected the migration to wait for the lock, got %v instead", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	list.Add("New task")
+	if err := list.Save(legacy); err != nil {
+		t.Fatal(err)
+	}
+	if err := lock.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	store, _, err := ws.Open(todo.DefaultListName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	migrated := todo.TodoList{}
+	if err := store.Load(&migrated); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(migrated.Items) != 2 {
+		t.Errorf("Expected both tasks, got %+v instead", migrated.Items)
+	}
+}
+
+func TestMoveItem(t *testing.T) {
+	from := newListOf(4)
+	from.SetParent(2, 1)
+	from.SetParent(3, 2)
+	from.Block(3, 4)
+	from.Block(2, 3)
+
+	to := newListOf(1)
+
+	moved, err := todo.MoveItem(from, to, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(moved) != 3 {
+		t.Fatalf("Expected 3 moved items, got %d instead", len(moved))
+	}
+
+	expectedTo := "[ ] 1: Task 1\n" +
+		"[ ] 2: Task 1\n" +
+		"  [ ] 3: Task 2\n" +
+		"    [ ] 4: Task 3\n"
+
+	if res := to.String(); res != expectedTo {
+		t.Errorf("Expected %q, got %q instead", expectedTo, res)
+	}
+
+	// The link between moved items is kept; the one to item 4 is dropped.
+	if !reflect.DeepEqual(moved[1].BlockedBy, []int{4}) || moved[2].BlockedBy != nil {
+		t.Errorf("Expected [4] and no blockers, got %v and %v instead", moved[1].BlockedBy, moved[2].BlockedBy)
+	}
+
+	if res := from.String(); res != "[ ] 4: Task 4\n" {
+		t.Errorf("Expected only item 4 to stay, got %q instead", res)
+	}
+
+	if _, err := todo.MoveItem(from, to, 1); !errors.Is(err, todo.ErrItemNotFound) {
+		t.Errorf("Expected %q, got %q instead", todo.ErrItemNotFound, err)
+	}
+}
@@ -0,0 +1,7 @@
+package main
+
+import "errors"
+
+var (
+	ErrInvalidData = errors.New("Invalid data")
+)
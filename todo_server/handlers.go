@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"mnishiguchi.com/todo"
+)
+
+// Serves the list kept in a file. Requests that change the list are handled
+// one at a time.
+type todoHandler struct {
+	filename string
+	mu       sync.Mutex
+}
+
+// Merges the state of a client into the list and replies with the result; see
+// todo.Remote.
+func (h *todoHandler) sync(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		replyError(w, req, http.StatusMethodNotAllowed, "Method not supported")
+		return
+	}
+
+	state := todo.SyncState{}
+	if err := json.NewDecoder(req.Body).Decode(&state); err != nil {
+		replyError(w, req, http.StatusBadRequest, fmt.Sprintf("%v: %v", ErrInvalidData, err))
+		return
+	}
+
+	reply := todo.SyncReply{}
+	list, err := h.update(func(l *todo.TodoList) error {
+		reply.Report = l.Merge(state.Items, state.Deleted)
+		l.PruneTombstones(time.Now().Add(-todo.TombstoneRetention))
+		return nil
+	})
+	if err != nil {
+		replyError(w, req, http.StatusInternalServerError, err.Error())
+		return
+	}
+	reply.State = todo.SyncState{Items: list.Items, Deleted: list.Deleted}
+
+	replyJSONContent(w, req, http.StatusOK, reply)
+}
+
+// Loads the list, applies the change and saves the list, stamping the fields
+// that changed for syncing. Returns the saved list.
+func (h *todoHandler) update(change func(*todo.TodoList) error) (*todo.TodoList, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	list := &todo.TodoList{}
+	if err := list.Get(h.filename); err != nil {
+		return nil, err
+	}
+
+	before, err := list.Clone()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := change(list); err != nil {
+		return nil, err
+	}
+	list.Stamp(before, time.Now())
+
+	if err := list.Save(h.filename); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func replyJSONContent(w http.ResponseWriter, req *http.Request, status int, resp interface{}) {
+	body, err := json.Marshal(resp)
+	if err != nil {
+		replyError(w, req, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+func replyError(w http.ResponseWriter, req *http.Request, status int, message string) {
+	replyTextContent(w, req, status, message)
+}
@@ -6,6 +6,8 @@ import (
 	"net/http"
 	"os"
 	"time"
+
+	"mnishiguchi.com/todo"
 )
 
 func main() {
@@ -38,6 +40,9 @@ func newMultiplexer(todoFile string) http.Handler {
 	m := http.NewServeMux()
 	m.HandleFunc("/", rootHandler)
 
+	h := &todoHandler{filename: todoFile}
+	m.HandleFunc(todo.SyncPath, h.sync)
+
 	return m
 }
 
@@ -1,11 +1,15 @@
 package main
 
 import (
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"strings"
 	"testing"
+
+	"mnishiguchi.com/todo"
 )
 
 func TestGet(t *testing.T) {
@@ -64,8 +68,19 @@ func TestGet(t *testing.T) {
 }
 
 func setupAPI(t *testing.T) (string, func()) {
-	t.Helper()                                  // Mark this test as a test helper.
-	s := httptest.NewServer(newMultiplexer("")) // Create a test server.
+	t.Helper() // Mark this test as a test helper.
+
+	// Start with a list of three items.
+	todoFile := filepath.Join(t.TempDir(), "todo_server.json")
+	list := &todo.TodoList{}
+	for i := 1; i <= 3; i++ {
+		list.Add(fmt.Sprintf("Task number %d", i))
+	}
+	if err := list.Save(todoFile); err != nil {
+		t.Fatal(err)
+	}
+
+	s := httptest.NewServer(newMultiplexer(todoFile)) // Create a test server.
 
 	return s.URL, func() { s.Close() }
 }
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"mnishiguchi.com/todo"
+)
+
+// A laptop with its own copy of the list that syncs with the server.
+type laptop struct {
+	list   *todo.TodoList
+	remote *todo.Remote
+}
+
+// Applies a change to the local list and stamps it as made at the time, the way
+// the todo CLI saves its changes.
+func (l *laptop) change(t *testing.T, at time.Time, f func(*todo.TodoList) error) {
+	t.Helper()
+
+	before, err := l.list.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f(l.list); err != nil {
+		t.Fatal(err)
+	}
+
+	l.list.Stamp(before, at)
+}
+
+// Syncs with the server and returns what was pushed and pulled.
+func (l *laptop) sync(t *testing.T) (string, string) {
+	t.Helper()
+
+	pushed, pulled, err := l.remote.Sync(l.list)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pushed.String(), pulled.String()
+}
+
+// Returns the ID of the item with the task.
+func (l *laptop) id(t *testing.T, task string) int {
+	t.Helper()
+
+	for _, item := range l.list.Items {
+		if item.Task == task {
+			return item.ID
+		}
+	}
+
+	t.Fatalf("Expected an item %q", task)
+	return 0
+}
+
+// Lists the done states, priorities and tasks of the items in alphabetical
+// order, since each copy of the list orders the items its own way.
+func (l *laptop) summary() string {
+	lines := []string{}
+	for _, item := range l.list.Items {
+		lines = append(lines, fmt.Sprintf("%t %q %s", item.Done, item.Priority, item.Task))
+	}
+	sort.Strings(lines)
+
+	return strings.Join(lines, "\n")
+}
+
+func TestSync(t *testing.T) {
+	url, cleanup := setupAPI(t)
+	defer cleanup()
+
+	a := &laptop{list: &todo.TodoList{}, remote: &todo.Remote{URL: url}}
+	b := &laptop{list: &todo.TodoList{}, remote: &todo.Remote{URL: url + "/"}}
+
+	// Every change below happens after the items of the server were created.
+	start := time.Now().Add(time.Minute)
+	at := func(minutes int) time.Time {
+		return start.Add(time.Duration(minutes) * time.Minute)
+	}
+
+	t.Run("PushAndPull", func(t *testing.T) {
+		a.change(t, at(0), func(l *todo.TodoList) error {
+			l.Add("Ship release")
+			return nil
+		})
+
+		pushed, pulled := a.sync(t)
+
+		if expected := "added: Ship release\n"; pushed != expected {
+			t.Errorf("Expected pushed %q, got %q instead", expected, pushed)
+		}
+
+		expected := "added: Task number 1\nadded: Task number 2\nadded: Task number 3\n"
+		if pulled != expected {
+			t.Errorf("Expected pulled %q, got %q instead", expected, pulled)
+		}
+
+		if _, pulled := b.sync(t); strings.Count(pulled, "added:") != 4 {
+			t.Errorf("Expected 4 items pulled, got %q instead", pulled)
+		}
+	})
+
+	t.Run("DifferentFields", func(t *testing.T) {
+		a.change(t, at(1), func(l *todo.TodoList) error {
+			return l.Complete(a.id(t, "Task number 1"))
+		})
+		b.change(t, at(2), func(l *todo.TodoList) error {
+			return l.Edit(b.id(t, "Task number 1"), "(A) Task number 1")
+		})
+
+		a.sync(t)
+		pushed, pulled := b.sync(t)
+		a.sync(t)
+
+		if expected := "updated: Task number 1 (priority)\n"; pushed != expected {
+			t.Errorf("Expected pushed %q, got %q instead", expected, pushed)
+		}
+		if expected := "updated: Task number 1 (done)\n"; pulled != expected {
+			t.Errorf("Expected pulled %q, got %q instead", expected, pulled)
+		}
+
+		if a.summary() != b.summary() {
+			t.Errorf("Expected the same lists, got %q and %q instead", a.summary(), b.summary())
+		}
+		if !strings.Contains(a.summary(), `true "A" Task number 1`) {
+			t.Errorf("Expected both changes kept, got %q instead", a.summary())
+		}
+	})
+
+	t.Run("LastWriterWins", func(t *testing.T) {
+		// b edits later but syncs first.
+		a.change(t, at(3), func(l *todo.TodoList) error {
+			return l.Edit(a.id(t, "Task number 2"), "Task number 2 from a")
+		})
+		b.change(t, at(4), func(l *todo.TodoList) error {
+			return l.Edit(b.id(t, "Task number 2"), "Task number 2 from b")
+		})
+
+		b.sync(t)
+		pushed, pulled := a.sync(t)
+
+		if pushed != "" {
+			t.Errorf("Expected nothing pushed, got %q instead", pushed)
+		}
+		if expected := "updated: Task number 2 from b (task)\n"; pulled != expected {
+			t.Errorf("Expected pulled %q, got %q instead", expected, pulled)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		a.change(t, at(5), func(l *todo.TodoList) error {
+			return l.Delete(a.id(t, "Ship release"))
+		})
+
+		a.sync(t)
+		if _, pulled := b.sync(t); pulled != "deleted: Ship release\n" {
+			t.Errorf("Expected %q, got %q instead", "deleted: Ship release\n", pulled)
+		}
+
+		expected := `false "" Task number 2 from b` + "\n" +
+			`false "" Task number 3` + "\n" +
+			`true "A" Task number 1`
+
+		if res := b.summary(); res != expected {
+			t.Errorf("Expected %q, got %q instead", expected, res)
+		}
+		if a.summary() != b.summary() {
+			t.Errorf("Expected the same lists, got %q and %q instead", a.summary(), b.summary())
+		}
+	})
+
+	t.Run("InSync", func(t *testing.T) {
+		if pushed, pulled := a.sync(t); pushed != "" || pulled != "" {
+			t.Errorf("Expected no changes, got %q and %q instead", pushed, pulled)
+		}
+	})
+}
+
+func TestSyncPrunesTombstones(t *testing.T) {
+	url, cleanup := setupAPI(t)
+	defer cleanup()
+
+	a := &laptop{list: &todo.TodoList{}, remote: &todo.Remote{URL: url}}
+	b := &laptop{list: &todo.TodoList{}, remote: &todo.Remote{URL: url}}
+	a.sync(t)
+	b.sync(t)
+
+	start := time.Now().Add(time.Minute)
+
+	// An archived item keeps its tombstone while the server has the item.
+	a.change(t, start, func(l *todo.TodoList) error {
+		if err := l.Complete(a.id(t, "Task number 1")); err != nil {
+			return err
+		}
+		_, err := l.Archive(&todo.TodoList{}, start.Add(time.Hour))
+		return err
+	})
+	a.sync(t)
+
+	if deleted := a.list.Deleted; len(deleted) != 1 || !deleted[0].Archived {
+		t.Fatalf("Expected an archived tombstone, got %+v instead", deleted)
+	}
+
+	// Once the server no longer has the item, the archived tombstone goes.
+	b.change(t, start.Add(time.Minute), func(l *todo.TodoList) error {
+		return l.Delete(b.id(t, "Task number 1"))
+	})
+	b.sync(t)
+	a.sync(t)
+
+	for _, tombstone := range a.list.Deleted {
+		if tombstone.Archived {
+			t.Errorf("Expected no archived tombstones, got %+v instead", a.list.Deleted)
+		}
+	}
+
+	// The server drops the tombstones of deletions older than the retention.
+	b.change(t, time.Now().Add(-todo.TombstoneRetention-time.Hour), func(l *todo.TodoList) error {
+		return l.Delete(b.id(t, "Task number 2"))
+	})
+	b.sync(t)
+
+	c := &laptop{list: &todo.TodoList{}, remote: &todo.Remote{URL: url}}
+	c.sync(t)
+
+	if deleted := c.list.Deleted; len(deleted) != 1 {
+		t.Errorf("Expected only the recent tombstone on the server, got %+v instead", deleted)
+	}
+}
+
+func TestSyncError(t *testing.T) {
+	url, cleanup := setupAPI(t)
+	defer cleanup()
+
+	remote := &todo.Remote{URL: url + "/todo"}
+	if _, _, err := remote.Sync(&todo.TodoList{}); !errors.Is(err, todo.ErrSync) {
+		t.Errorf("Expected %q, got %q instead", todo.ErrSync, err)
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Holds every counter computed in a single pass over the input.
+type Counts struct {
+	Lines         int // number of lines
+	Words         int // number of whitespace-separated words
+	Bytes         int // number of bytes
+	Chars         int // number of UTF-8 characters (runes)
+	MaxLineLength int // length of the longest line in characters
+}
+
+// Accumulates other counts into c. The longest line is the maximum of both
+// rather than a sum.
+func (c *Counts) add(other Counts) {
+	c.Lines += other.Lines
+	c.Words += other.Words
+	c.Bytes += other.Bytes
+	c.Chars += other.Chars
+
+	if other.MaxLineLength > c.MaxLineLength {
+		c.MaxLineLength = other.MaxLineLength
+	}
+}
+
+// A column that can be selected for output.
+type column int
+
+const (
+	colLines column = iota
+	colWords
+	colChars
+	colBytes
+	colMaxLineLength
+)
+
+// All the columns in the order they are printed, following coreutils.
+var allColumns = []column{colLines, colWords, colChars, colBytes, colMaxLineLength}
+
+// Returns the name of the column used in machine-readable output.
+func (col column) String() string {
+	switch col {
+	case colLines:
+		return "lines"
+	case colWords:
+		return "words"
+	case colChars:
+		return "chars"
+	case colBytes:
+		return "bytes"
+	case colMaxLineLength:
+		return "max_line_length"
+	}
+
+	return "unknown"
+}
+
+// Picks the counter that corresponds to the column.
+func (col column) value(c Counts) int {
+	switch col {
+	case colLines:
+		return c.Lines
+	case colWords:
+		return c.Words
+	case colChars:
+		return c.Chars
+	case colBytes:
+		return c.Bytes
+	case colMaxLineLength:
+		return c.MaxLineLength
+	}
+
+	return 0
+}
+
+// Counts lines, words, bytes, characters and the longest line in one pass.
+//
+// The input is streamed through a counter instead of a bufio.Scanner, so there
+// is no limit on the length of a line and any read error is returned to the
+// caller rather than silently ending the count.
+//
+// When a split function is given, the words are the tokens it produces instead
+// of whitespace-separated words. The other counters are computed from the same
+// pass over the input.
+func count(r io.Reader, split bufio.SplitFunc) (Counts, error) {
+	c := counter{}
+
+	if split == nil {
+		_, err := io.Copy(&c, r)
+		return c.result(), err
+	}
+
+	// Feed everything the scanner reads to the counter as well.
+	scanner := bufio.NewScanner(io.TeeReader(r, &c))
+	scanner.Buffer(make([]byte, 0, initialTokenSize), maxTokenSize)
+	scanner.Split(split)
+
+	tokens := 0
+	for scanner.Scan() {
+		tokens++
+	}
+
+	if err := scanner.Err(); err != nil {
+		return c.result(), err
+	}
+
+	res := c.result()
+	res.Words = tokens
+
+	return res, nil
+}
+
+const (
+	initialTokenSize = 64 * 1024
+	maxTokenSize     = 1 << 30
+)
+
+// A streaming counter that keeps track of the state needed to count input that
+// arrives piece by piece. It implements io.Writer.
+type counter struct {
+	counts     Counts
+	inWord     bool   // the previous rune was part of a word
+	inLine     bool   // the current line has content that is not counted yet
+	lineLength int    // characters seen so far in the current line
+	trailingCR int    // carriage returns at the end of the current line
+	partial    []byte // the beginning of a rune split across writes
+}
+
+// Counts the given bytes. A multi-byte character that is split across two
+// writes is kept until the rest of it arrives.
+func (c *counter) Write(p []byte) (int, error) {
+	data := p
+	if len(c.partial) > 0 {
+		data = append(c.partial, p...)
+		c.partial = nil
+	}
+
+	for len(data) > 0 {
+		// Fast path for ASCII.
+		if data[0] < utf8.RuneSelf {
+			c.addRune(rune(data[0]), 1)
+			data = data[1:]
+			continue
+		}
+
+		if !utf8.FullRune(data) {
+			c.partial = append([]byte{}, data...)
+			break
+		}
+
+		ru, size := utf8.DecodeRune(data)
+		c.addRune(ru, size)
+		data = data[size:]
+	}
+
+	return len(p), nil
+}
+
+// Updates the counters with a single rune that is size bytes long.
+func (c *counter) addRune(ru rune, size int) {
+	c.counts.Bytes += size
+	c.counts.Chars++
+
+	if ru == '\n' {
+		c.endLine()
+		c.inWord = false
+		return
+	}
+
+	c.inLine = true
+	c.lineLength++
+
+	// The line ending, including a carriage return, does not count towards the
+	// line length.
+	if ru == '\r' {
+		c.trailingCR++
+	} else {
+		c.trailingCR = 0
+	}
+
+	if unicode.IsSpace(ru) {
+		c.inWord = false
+		return
+	}
+
+	if !c.inWord {
+		c.counts.Words++
+		c.inWord = true
+	}
+}
+
+// Counts the current line and resets the line state.
+func (c *counter) endLine() {
+	c.counts.Lines++
+
+	if length := c.lineLength - c.trailingCR; length > c.counts.MaxLineLength {
+		c.counts.MaxLineLength = length
+	}
+
+	c.inLine = false
+	c.lineLength = 0
+	c.trailingCR = 0
+}
+
+// Returns the counts so far. A last line without a newline is counted as a
+// line too, without changing the state of the counter.
+func (c *counter) result() Counts {
+	final := *c
+
+	// An incomplete character at the end of the input is counted byte by byte,
+	// the same way utf8.RuneCount does.
+	for range c.partial {
+		final.addRune(utf8.RuneError, 1)
+	}
+
+	if final.inLine {
+		final.endLine()
+	}
+
+	return final.counts
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+)
+
+var (
+	// ID1, ID2 and the deflate compression method. See RFC 1952.
+	gzipMagic = []byte{0x1f, 0x8b, 0x08}
+
+	// "BZh", the block size, and the magic number of either the first block or
+	// the end of an empty stream.
+	bzip2Magic = regexp.MustCompile(`^BZh[1-9](\x31\x41\x59\x26\x53\x59|\x17\x72\x45\x38\x50\x90)`)
+)
+
+// The number of bytes needed to detect any supported compression format.
+const magicLen = 10
+
+// Detects gzip and bzip2 input by its magic bytes and returns a reader of the
+// decompressed stream. Any other input is returned as is.
+func decompress(r io.Reader) (io.Reader, error) {
+	reader := bufio.NewReader(r)
+
+	// Peek returns io.EOF for input that is shorter than the magic bytes.
+	magic, err := reader.Peek(magicLen)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		return gzip.NewReader(reader)
+	case bzip2Magic.Match(magic):
+		return bzip2.NewReader(reader), nil
+	}
+
+	return reader, nil
+}
+
+// A file whose content is read through a decompressing reader.
+type inputFile struct {
+	io.Reader
+	file *os.File
+}
+
+func (f *inputFile) Close() error {
+	return f.file.Close()
+}
+
+// Opens the provided file name for counting. Compressed files are decompressed
+// unless raw is set.
+func openInput(fileName string, raw bool) (io.ReadCloser, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	if raw {
+		return f, nil
+	}
+
+	r, err := decompress(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", fileName, err)
+	}
+
+	return &inputFile{Reader: r, file: f}, nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"testing"
+)
+
+func TestRunCompressed(t *testing.T) {
+	testCases := []struct {
+		name     string
+		files    []string
+		raw      bool
+		expected string
+	}{
+		{name: "Gzip",
+			files:    []string{"testdata/file2.txt.gz"},
+			expected: "       3        6 testdata/file2.txt.gz\n"},
+		{name: "Bzip2",
+			files:    []string{"testdata/file2.txt.bz2"},
+			expected: "       3        6 testdata/file2.txt.bz2\n"},
+		{name: "Mixed",
+			files: []string{"testdata/file2.txt", "testdata/file2.txt.gz", "testdata/file2.txt.bz2"},
+			expected: "       3        6 testdata/file2.txt\n" +
+				"       3        6 testdata/file2.txt.gz\n" +
+				"       3        6 testdata/file2.txt.bz2\n" +
+				"       9       18 total\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var outWriter, errWriter bytes.Buffer
+			cfg := config{
+				columns:   []column{colLines, colWords},
+				jobs:      1,
+				format:    "text",
+				raw:       tc.raw,
+				errWriter: &errWriter,
+			}
+
+			if err := run(tc.files, nil, &outWriter, cfg); err != nil {
+				t.Fatal(err)
+			}
+
+			if outWriter.String() != tc.expected {
+				t.Errorf("Expected %q, got %q instead", tc.expected, outWriter.String())
+			}
+		})
+	}
+}
+
+func TestRunCompressedStdin(t *testing.T) {
+	var compressed bytes.Buffer
+	gzWriter := gzip.NewWriter(&compressed)
+	if _, err := gzWriter.Write([]byte("one two\nthree\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := gzWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// With -raw, the compressed bytes themselves are counted.
+	rawCounts, err := count(bytes.NewReader(compressed.Bytes()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name     string
+		raw      bool
+		expected string
+	}{
+		{name: "Decompressed", raw: false, expected: "3\n"},
+		{name: "Raw", raw: true, expected: fmt.Sprintln(rawCounts.Words)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var outWriter bytes.Buffer
+			in := bytes.NewReader(compressed.Bytes())
+			cfg := config{columns: []column{colWords}, jobs: 1, format: "text", raw: tc.raw}
+
+			if err := run(nil, in, &outWriter, cfg); err != nil {
+				t.Fatal(err)
+			}
+
+			if outWriter.String() != tc.expected {
+				t.Errorf("Expected %q, got %q instead", tc.expected, outWriter.String())
+			}
+		})
+	}
+}
+
+func TestDecompressPlainText(t *testing.T) {
+	// Text that starts like a bzip2 header must not be mistaken for it.
+	for _, input := range []string{"", "a", "BZh9 is not bzip2\n"} {
+		r, err := decompress(bytes.NewBufferString(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := count(r, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if res.Bytes != len(input) {
+			t.Errorf("Expected %d bytes for %q, got %d instead", len(input), input, res.Bytes)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package main
+
+import "errors"
+
+var (
+	ErrCountFailed     = errors.New("Cannot count some files")
+	ErrInvalidJobs     = errors.New("Invalid number of jobs")
+	ErrInvalidFormat   = errors.New("Invalid output format")
+	ErrInvalidSplit    = errors.New("Invalid split expression")
+	ErrInvalidMaxKeys  = errors.New("Invalid maximum number of keys")
+	ErrInvalidFreq     = errors.New("Invalid number of frequent words")
+	ErrInvalidGlob     = errors.New("Invalid glob pattern")
+	ErrRecursiveArgs   = errors.New("Cannot combine -r with file arguments")
+	ErrRecursiveFreq   = errors.New("Cannot combine -r with -freq")
+	ErrBinaryFile      = errors.New("Binary file")
+	ErrFollowArgs      = errors.New("Follow mode needs exactly one file")
+	ErrFollowOption    = errors.New("Follow mode does not support the option")
+	ErrInvalidInterval = errors.New("Invalid interval")
+)
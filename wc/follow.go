@@ -0,0 +1,235 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Follows a growing file, tail-style, and keeps its counts up to date.
+//
+// The counts are cumulative since the follower started, so lines that were
+// counted before the file was truncated or rotated are kept.
+type follower struct {
+	fileName string
+	file     *os.File
+	info     os.FileInfo
+	offset   int64    // bytes of the current file counted so far
+	previous Counts   // counts of the files replaced by rotation or truncation
+	current  *counter // counts of the current file
+	errOut   io.Writer
+}
+
+func newFollower(fileName string, errWriter io.Writer) (*follower, error) {
+	f := &follower{fileName: fileName, errOut: errWriter}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// Opens the file name and starts counting it from the beginning.
+func (f *follower) open() error {
+	file, err := os.Open(f.fileName)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	f.file = file
+	f.info = info
+	f.offset = 0
+	f.current = &counter{}
+
+	return nil
+}
+
+// Keeps the counts of the current file and starts a new count. A last line
+// without a newline is counted as a line.
+func (f *follower) reset() {
+	f.previous.add(f.current.result())
+	f.current = &counter{}
+	f.offset = 0
+}
+
+// Reads everything appended since the last poll, handling truncation and
+// rotation of the file.
+func (f *follower) poll() error {
+	info, err := os.Stat(f.fileName)
+
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		// Rotated away and not recreated yet; keep reading the old file.
+		return f.readNew()
+
+	case err != nil:
+		return err
+
+	case !os.SameFile(info, f.info):
+		// Rotated; finish the old file before switching to the new one.
+		if err := f.readNew(); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(f.errOut, "%s: file rotated\n", f.fileName)
+		f.reset()
+		f.file.Close()
+
+		if err := f.open(); err != nil {
+			return err
+		}
+
+	case info.Size() < f.offset:
+		fmt.Fprintf(f.errOut, "%s: file truncated\n", f.fileName)
+		f.reset()
+
+		if _, err := f.file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+	}
+
+	return f.readNew()
+}
+
+// Counts the bytes between the current offset and the end of the file.
+func (f *follower) readNew() error {
+	n, err := io.Copy(f.current, f.file)
+	f.offset += n
+
+	return err
+}
+
+// Returns the cumulative counts.
+func (f *follower) counts() Counts {
+	c := f.previous
+	c.add(f.current.result())
+
+	return c
+}
+
+func (f *follower) close() error {
+	return f.file.Close()
+}
+
+// Formats the selected counters followed by the name and a rate of lines per
+// second.
+func formatFollowRow(c Counts, columns []column, name string, linesPerSecond float64, note string) string {
+	fields := make([]string, 0, len(columns)+2)
+	for _, col := range columns {
+		fields = append(fields, fmt.Sprintf("%8d", col.value(c)))
+	}
+
+	fields = append(fields, name, fmt.Sprintf("%.1f lines/s%s", linesPerSecond, note))
+
+	return strings.Join(fields, " ")
+}
+
+// Calculates the rate, avoiding a division by zero.
+func linesPerSecond(lines int, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+
+	return float64(lines) / elapsed.Seconds()
+}
+
+// Counts the file, then prints updated counts and the rate of new lines every
+// time chTick fires until a signal is received on chStop. A final summary with
+// the average rate is printed before returning.
+func follow(fileName string, outWriter io.Writer, cfg config, start time.Time, chTick <-chan time.Time, chStop <-chan os.Signal) error {
+	f, err := newFollower(fileName, cfg.errWriter)
+	if err != nil {
+		return err
+	}
+	defer f.close()
+
+	if err := f.poll(); err != nil {
+		return err
+	}
+
+	// Lines that already exist do not count towards the rates.
+	initialLines := f.counts().Lines
+	lastTick, lastLines := start, initialLines
+
+	for {
+		select {
+		case now := <-chTick:
+			if err := f.poll(); err != nil {
+				return err
+			}
+
+			c := f.counts()
+			rate := linesPerSecond(c.Lines-lastLines, now.Sub(lastTick))
+			lastTick, lastLines = now, c.Lines
+
+			if _, err := fmt.Fprintln(outWriter, formatFollowRow(c, cfg.columns, fileName, rate, "")); err != nil {
+				return err
+			}
+
+		case <-chStop:
+			// Pick up anything written since the last tick.
+			if err := f.poll(); err != nil {
+				return err
+			}
+
+			c := f.counts()
+			elapsed := time.Since(start)
+			note := fmt.Sprintf(" on average over %s", elapsed.Round(time.Second))
+			_, err := fmt.Fprintln(outWriter, formatFollowRow(c, cfg.columns, "total", linesPerSecond(c.Lines-initialLines, elapsed), note))
+
+			return err
+		}
+	}
+}
+
+// Follows the file until SIGINT or SIGTERM is received.
+func runFollow(fileNames []string, outWriter io.Writer, cfg config) error {
+	if len(fileNames) != 1 {
+		return ErrFollowArgs
+	}
+
+	if cfg.interval <= 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidInterval, cfg.interval)
+	}
+
+	// Follow mode prints plain counts of the raw bytes with the default word
+	// definition.
+	unsupported := []struct {
+		option string
+		set    bool
+	}{
+		{"-split", cfg.split != nil},
+		{"-format", cfg.format != "" && cfg.format != "text"},
+		{"-raw", cfg.raw},
+		{"-freq", cfg.freq.top > 0},
+		{"-r", cfg.root != ""},
+		{"-include", len(cfg.include) > 0},
+		{"-exclude", len(cfg.exclude) > 0},
+	}
+	for _, u := range unsupported {
+		if u.set {
+			return fmt.Errorf("%w: %s", ErrFollowOption, u.option)
+		}
+	}
+
+	ticker := time.NewTicker(cfg.interval)
+	defer ticker.Stop()
+
+	// A buffered channel of size 1 so that a signal is not missed.
+	chSignal := make(chan os.Signal, 1)
+	signal.Notify(chSignal, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(chSignal)
+
+	return follow(fileNames[0], outWriter, cfg, time.Now(), ticker.C, chSignal)
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFollow(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(fileName, []byte("one\ntwo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	appendLines := func(content string) {
+		t.Helper()
+		f, err := os.OpenFile(fileName, os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		if _, err := f.WriteString(content); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	chTick := make(chan time.Time)
+	chStop := make(chan os.Signal)
+	chErr := make(chan error)
+
+	// Every line is received before the file is changed again, so that the
+	// follower and the test do not race.
+	outWriter := lineWriter(make(chan string))
+	var errWriter bytes.Buffer
+	cfg := config{columns: []column{colLines}, errWriter: &errWriter}
+
+	tick := func(now time.Time, expected string) {
+		t.Helper()
+		chTick <- now
+		if res := <-outWriter; res != expected {
+			t.Errorf("Expected %q, got %q instead", expected, res)
+		}
+	}
+
+	go func() {
+		chErr <- follow(fileName, outWriter, cfg, start, chTick, chStop)
+	}()
+
+	// The initial content is counted but does not count towards the rate.
+	tick(start.Add(1*time.Second), "       2 "+fileName+" 0.0 lines/s\n")
+
+	// Lines appended over 2 seconds.
+	appendLines("three\nfour\nfive\nsix\n")
+	tick(start.Add(3*time.Second), "       6 "+fileName+" 2.0 lines/s\n")
+
+	// A truncated file keeps the lines counted so far.
+	if err := os.Truncate(fileName, 0); err != nil {
+		t.Fatal(err)
+	}
+	appendLines("seven\n")
+	tick(start.Add(4*time.Second), "       7 "+fileName+" 1.0 lines/s\n")
+
+	// A rotated file is finished before the new one is counted.
+	appendLines("eight\n")
+	if err := os.Rename(fileName, fileName+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(fileName, []byte("nine\nten\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tick(start.Add(5*time.Second), "      10 "+fileName+" 3.0 lines/s\n")
+
+	// The final summary includes lines written after the last tick.
+	appendLines("eleven\n")
+	chStop <- os.Interrupt
+	if res := <-outWriter; !strings.HasPrefix(res, "      11 total ") {
+		t.Errorf("Expected a final summary, got %q instead", res)
+	}
+
+	if err := <-chErr; err != nil {
+		t.Fatal(err)
+	}
+
+	for _, msg := range []string{"file truncated", "file rotated"} {
+		if !strings.Contains(errWriter.String(), msg) {
+			t.Errorf("Expected %q to be reported, got %q instead", msg, errWriter.String())
+		}
+	}
+}
+
+func TestRunFollowArgs(t *testing.T) {
+	var outWriter bytes.Buffer
+	cfg := config{columns: allColumns, jobs: 1, format: "text", follow: true, interval: time.Second}
+
+	err := run([]string{"testdata/file1.txt", "testdata/file2.txt"}, nil, &outWriter, cfg)
+	if !errors.Is(err, ErrFollowArgs) {
+		t.Errorf("Expected error %q, got %v instead", ErrFollowArgs, err)
+	}
+
+	cfg.interval = 0
+	err = run([]string{"testdata/file1.txt"}, nil, &outWriter, cfg)
+	if !errors.Is(err, ErrInvalidInterval) {
+		t.Errorf("Expected error %q, got %v instead", ErrInvalidInterval, err)
+	}
+
+	testCases := []struct {
+		name   string
+		change func(cfg *config)
+	}{
+		{name: "Split", change: func(cfg *config) { cfg.split = func() bufio.SplitFunc { return bufio.ScanRunes } }},
+		{name: "Format", change: func(cfg *config) { cfg.format = "json" }},
+		{name: "Raw", change: func(cfg *config) { cfg.raw = true }},
+		{name: "Freq", change: func(cfg *config) { cfg.freq.top = 3 }},
+		{name: "Include", change: func(cfg *config) { cfg.include = globList{"*.txt"} }},
+		{name: "Exclude", change: func(cfg *config) { cfg.exclude = globList{"*.log"} }},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := config{columns: allColumns, jobs: 1, format: "text", follow: true, interval: time.Second}
+			tc.change(&cfg)
+
+			err := run([]string{"testdata/file1.txt"}, nil, &outWriter, cfg)
+			if !errors.Is(err, ErrFollowOption) {
+				t.Errorf("Expected error %q, got %v instead", ErrFollowOption, err)
+			}
+		})
+	}
+}
+
+// An io.Writer that sends each write to a channel.
+type lineWriter chan string
+
+func (w lineWriter) Write(p []byte) (int, error) {
+	w <- string(p)
+	return len(p), nil
+}
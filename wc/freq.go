@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bufio"
+	"container/heap"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Options of the frequency mode.
+type freqOptions struct {
+	top       int             // number of tokens to print; 0 disables the mode
+	foldCase  bool            // count tokens case-insensitively
+	stopWords map[string]bool // tokens that are not counted
+	maxKeys   int             // maximum number of distinct tokens kept in memory
+}
+
+// A token and the number of times it was seen.
+type tokenCount struct {
+	token string
+	count int
+	index int // position in the heap
+}
+
+// Counts token frequencies with a bounded number of distinct keys.
+//
+// It implements the Space-Saving algorithm: once maxKeys tokens are tracked, a
+// new token replaces the least frequent one and inherits its count. Counts may
+// then be overestimated, but any token that is more frequent than the total
+// divided by maxKeys is guaranteed to be kept.
+type freqCounter struct {
+	maxKeys int
+	tokens  map[string]*tokenCount
+	minHeap tokenHeap
+}
+
+func newFreqCounter(maxKeys int) *freqCounter {
+	return &freqCounter{
+		maxKeys: maxKeys,
+		tokens:  map[string]*tokenCount{},
+	}
+}
+
+// Counts one occurrence of the token.
+func (f *freqCounter) add(token string) {
+	if tc, ok := f.tokens[token]; ok {
+		tc.count++
+		heap.Fix(&f.minHeap, tc.index)
+		return
+	}
+
+	if len(f.minHeap) < f.maxKeys {
+		tc := &tokenCount{token: token, count: 1}
+		f.tokens[token] = tc
+		heap.Push(&f.minHeap, tc)
+		return
+	}
+
+	// Evict the least frequent token.
+	tc := f.minHeap[0]
+	delete(f.tokens, tc.token)
+	tc.token = token
+	tc.count++
+	f.tokens[token] = tc
+	heap.Fix(&f.minHeap, tc.index)
+}
+
+// Returns the n most frequent tokens, most frequent first. Tokens with the same
+// count are sorted alphabetically.
+func (f *freqCounter) top(n int) []tokenCount {
+	res := make([]tokenCount, 0, len(f.minHeap))
+	for _, tc := range f.minHeap {
+		res = append(res, *tc)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].count != res[j].count {
+			return res[i].count > res[j].count
+		}
+		return res[i].token < res[j].token
+	})
+
+	if len(res) > n {
+		res = res[:n]
+	}
+
+	return res
+}
+
+// A min-heap of token counts implementing heap.Interface.
+type tokenHeap []*tokenCount
+
+func (h tokenHeap) Len() int           { return len(h) }
+func (h tokenHeap) Less(i, j int) bool { return h[i].count < h[j].count }
+
+func (h tokenHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *tokenHeap) Push(x interface{}) {
+	tc := x.(*tokenCount)
+	tc.index = len(*h)
+	*h = append(*h, tc)
+}
+
+func (h *tokenHeap) Pop() interface{} {
+	old := *h
+	tc := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return tc
+}
+
+// Tokenizes the input with the same word splitter as count and adds every
+// token to the frequency counter.
+func countFreq(r io.Reader, split bufio.SplitFunc, opts freqOptions, f *freqCounter) error {
+	if split == nil {
+		split = bufio.ScanWords
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, initialTokenSize), maxTokenSize)
+	scanner.Split(split)
+
+	for scanner.Scan() {
+		token := scanner.Text()
+		if opts.foldCase {
+			token = strings.ToLower(token)
+		}
+
+		if opts.stopWords[token] {
+			continue
+		}
+
+		f.add(token)
+	}
+
+	return scanner.Err()
+}
+
+// Reads whitespace-separated stop words from the provided file name.
+func loadStopWords(fileName string, foldCase bool) (map[string]bool, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	stopWords := map[string]bool{}
+
+	scanner := bufio.NewScanner(f)
+	scanner.Split(bufio.ScanWords)
+	for scanner.Scan() {
+		word := scanner.Text()
+		if foldCase {
+			word = strings.ToLower(word)
+		}
+		stopWords[word] = true
+	}
+
+	return stopWords, scanner.Err()
+}
+
+// Prints the most frequent tokens of STDIN, or of all the given files combined.
+func runFreq(fileNames []string, inReader io.Reader, outWriter io.Writer, cfg config) error {
+	opts := cfg.freq
+
+	if cfg.format != "text" {
+		return fmt.Errorf("%w: %s is not supported in frequency mode", ErrInvalidFormat, cfg.format)
+	}
+
+	if opts.maxKeys < opts.top {
+		return fmt.Errorf("%w: %d is less than the %d tokens to print", ErrInvalidMaxKeys, opts.maxKeys, opts.top)
+	}
+
+	f := newFreqCounter(opts.maxKeys)
+	failed := false
+
+	if len(fileNames) == 0 {
+		if !cfg.raw {
+			var err error
+			if inReader, err = decompress(inReader); err != nil {
+				return err
+			}
+		}
+
+		if err := countFreq(inReader, cfg.split.new(), opts, f); err != nil {
+			return err
+		}
+	}
+
+	for _, fileName := range fileNames {
+		if err := countFreqFile(fileName, cfg, f); err != nil {
+			// Report the failure but keep counting the other files.
+			fmt.Fprintln(cfg.errWriter, err)
+			failed = true
+		}
+	}
+
+	for _, tc := range f.top(opts.top) {
+		if _, err := fmt.Fprintf(outWriter, "%8d %s\n", tc.count, tc.token); err != nil {
+			return err
+		}
+	}
+
+	if failed {
+		return ErrCountFailed
+	}
+
+	return nil
+}
+
+// Opens the provided file name and adds its tokens to the frequency counter.
+func countFreqFile(fileName string, cfg config, f *freqCounter) error {
+	file, err := openInput(fileName, cfg.raw)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := countFreq(file, cfg.split.new(), cfg.freq, f); err != nil {
+		return fmt.Errorf("%s: %w", fileName, err)
+	}
+
+	return nil
+}
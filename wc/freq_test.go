@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestRunFreq(t *testing.T) {
+	input := "The cat and the dog. The cat sat, and the dog ran\n"
+
+	testCases := []struct {
+		name        string
+		files       []string
+		opts        freqOptions
+		expected    string
+		expectedErr error
+	}{
+		{name: "Top3",
+			opts:     freqOptions{top: 3, maxKeys: 100},
+			expected: "       2 The\n       2 and\n       2 cat\n"},
+		{name: "FoldCase",
+			opts:     freqOptions{top: 1, foldCase: true, maxKeys: 100},
+			expected: "       4 the\n"},
+		{name: "StopWords",
+			opts: freqOptions{
+				top:       2,
+				foldCase:  true,
+				stopWords: map[string]bool{"the": true, "and": true},
+				maxKeys:   100,
+			},
+			expected: "       2 cat\n       1 dog\n"},
+		{name: "Files",
+			files:    []string{"testdata/file1.txt", "testdata/file2.txt"},
+			opts:     freqOptions{top: 2, maxKeys: 100},
+			expected: "       1 five\n       1 four\n"},
+		{name: "InvalidMaxKeys",
+			opts:        freqOptions{top: 10, maxKeys: 5},
+			expected:    "",
+			expectedErr: ErrInvalidMaxKeys},
+		{name: "NegativeTop",
+			opts:        freqOptions{top: -1, maxKeys: 100},
+			expected:    "",
+			expectedErr: ErrInvalidFreq},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var outWriter, errWriter bytes.Buffer
+			cfg := config{jobs: 1, format: "text", freq: tc.opts, errWriter: &errWriter}
+
+			err := run(tc.files, bytes.NewBufferString(input), &outWriter, cfg)
+
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("Expected error %v, got %v instead", tc.expectedErr, err)
+			}
+
+			if outWriter.String() != tc.expected {
+				t.Errorf("Expected %q, got %q instead", tc.expected, outWriter.String())
+			}
+		})
+	}
+}
+
+func TestFreqCounterBounded(t *testing.T) {
+	f := newFreqCounter(10)
+
+	// A few heavy hitters among many distinct rare tokens.
+	for i := 0; i < 1000; i++ {
+		f.add("heavy")
+		f.add(fmt.Sprintf("rare%d", i))
+		if i%2 == 0 {
+			f.add("medium")
+		}
+	}
+
+	if len(f.tokens) > 10 {
+		t.Errorf("Expected at most 10 keys, got %d instead", len(f.tokens))
+	}
+
+	top := f.top(2)
+	if len(top) != 2 || top[0].token != "heavy" || top[1].token != "medium" {
+		t.Fatalf("Expected heavy and medium as the top tokens, got %+v instead", top)
+	}
+
+	// Counts are never underestimated.
+	if top[0].count < 1000 || top[1].count < 500 {
+		t.Errorf("Expected counts of at least 1000 and 500, got %+v instead", top)
+	}
+}
+
+func TestLoadStopWords(t *testing.T) {
+	stopWords, err := loadStopWords("testdata/stopwords.txt", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(stopWords) != 2 || !stopWords["the"] || !stopWords["and"] {
+		t.Errorf("Expected the and and, got %v instead", stopWords)
+	}
+}
@@ -1,53 +1,288 @@
 package main
 
 import (
-	"bufio"
 	"flag"
 	"fmt"
 	"io"
 	"os"
+	"time"
 )
 
+type config struct {
+	columns   []column      // counters to print, in order
+	jobs      int           // number of files counted concurrently
+	format    string        // output format: text, json or csv
+	split     splitter      // custom word definition; nil for whitespace
+	freq      freqOptions   // options of the frequency mode
+	root      string        // directory to count recursively
+	include   globList      // patterns of files to count in recursive mode
+	exclude   globList      // patterns of files and directories to skip
+	raw       bool          // count compressed input without decompressing
+	follow    bool          // keep counting a growing file
+	interval  time.Duration // how often counts are printed in follow mode
+	errWriter io.Writer     // where per-file errors are reported
+}
+
 /*
-Counts the number of words in a given text.
+Counts lines, words, characters and bytes in a given text.
 
 ## Examples
 
-    # Count words
+    # Print all the counters
     ❯ cat main.go | ./wc
-    125
+          93     305    2327    2327      78
 
     # Count lines
     ❯ cat main.go | ./wc -l
-    48
+    93
+
+    # Count lines and words of multiple files
+    ❯ ./wc -l -w main.go count.go
+          93     305 main.go
+         112     439 count.go
+         205     744 total
+
+    # Count many files on 8 goroutines
+    ❯ ./wc -j 8 logs/*.log
+
+    # Print machine-readable output
+    ❯ ./wc -l -format json main.go count.go
+    {"files":[{"lines":93,"name":"main.go"},{"lines":112,"name":"count.go"}],"total":{"lines":205}}
+
+    # Count comma-separated records
+    ❯ echo "a,b,,c" | ./wc -w -split ','
+    3
+
+    # Count identifiers
+    ❯ cat main.go | ./wc -w -split '[A-Za-z_][A-Za-z0-9_]*' -split-match
+    402
+
+    # Print the 3 most common words, ignoring case and stop words
+    ❯ ./wc -freq 3 -freq-ignore-case -freq-stop-words stopwords.txt README.md
+          12 count
+           9 files
+           7 words
+
+    # Count lines of Go files in a directory tree, grouped by extension
+    ❯ ./wc -l -r ./src -include '*.go' -exclude 'vendor/**'
+        1520 .go
+        1520 total
+
+    # Count lines of rotated logs, decompressing gzip and bzip2 files
+    ❯ ./wc -l app.log app.log.1.gz app.log.2.bz2
+
+    # Keep counting lines appended to a log file until Ctrl-C
+    ❯ ./wc -l -f -interval 5s app.log
+        1200 app.log 0.0 lines/s
+        1262 app.log 12.4 lines/s
+        1262 total 6.2 lines/s on average over 10s
 */
 func main() {
-	// Define a boolean flag -l to count lines instead of words.
-	forLines := flag.Bool("l", false, "Count lines")
+	// Define a boolean flag per counter. All the counters are printed when none
+	// of them is set.
+	forLines := flag.Bool("l", false, "Print the line counts")
+	forWords := flag.Bool("w", false, "Print the word counts")
+	forBytes := flag.Bool("c", false, "Print the byte counts")
+	forChars := flag.Bool("m", false, "Print the character counts")
+	forMaxLineLength := flag.Bool("L", false, "Print the maximum line length")
+	jobs := flag.Int("j", 1, "Number of files to count concurrently")
+	format := flag.String("format", "text", "Output format: text, json or csv")
+	splitExpr := flag.String("split", "", "Regular expression delimiting the words")
+	splitMatch := flag.Bool("split-match", false, "Count the matches of -split as words instead")
+	freqTop := flag.Int("freq", 0, "Print the N most frequent words instead of counts")
+	freqFoldCase := flag.Bool("freq-ignore-case", false, "Ignore case in -freq mode")
+	freqStopWords := flag.String("freq-stop-words", "", "File with words to ignore in -freq mode")
+	freqMaxKeys := flag.Int("freq-max-keys", 100000, "Maximum number of distinct words kept in -freq mode")
+	root := flag.String("r", "", "Count the files under this directory recursively")
+	include := globList{}
+	flag.Var(&include, "include", "Glob of files to count with -r; can be repeated")
+	exclude := globList{}
+	flag.Var(&exclude, "exclude", "Glob of files or directories to skip with -r; can be repeated")
+	raw := flag.Bool("raw", false, "Do not decompress gzip and bzip2 input")
+	follow := flag.Bool("f", false, "Follow a growing file and print live counts")
+	interval := flag.Duration("interval", time.Second, "How often counts are printed with -f")
 
 	// Parse the flags provided by the user.
 	flag.Parse()
 
-	// Print the word count.
-	fmt.Println(count(os.Stdin, *forLines))
+	var split splitter
+	if *splitExpr != "" {
+		var err error
+		if split, err = newRegexpSplit(*splitExpr, *splitMatch); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	var stopWords map[string]bool
+	if *freqStopWords != "" {
+		var err error
+		if stopWords, err = loadStopWords(*freqStopWords, *freqFoldCase); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	freq := freqOptions{
+		top:       *freqTop,
+		foldCase:  *freqFoldCase,
+		stopWords: stopWords,
+		maxKeys:   *freqMaxKeys,
+	}
+
+	cfg := config{
+		columns:   selectColumns(*forLines, *forWords, *forChars, *forBytes, *forMaxLineLength),
+		jobs:      *jobs,
+		format:    *format,
+		split:     split,
+		freq:      freq,
+		root:      *root,
+		include:   include,
+		exclude:   exclude,
+		raw:       *raw,
+		follow:    *follow,
+		interval:  *interval,
+		errWriter: os.Stderr,
+	}
+
+	if err := run(flag.Args(), os.Stdin, os.Stdout, cfg); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 }
 
-func count(r io.Reader, forLines bool) int {
-	// Prepare a scanner that reads text from a reader (such as files).
-	scanner := bufio.NewScanner(r)
+// Determines the columns to print from the flags, keeping the coreutils order.
+func selectColumns(lines, words, chars, bytes, maxLineLength bool) []column {
+	selected := map[column]bool{
+		colLines:         lines,
+		colWords:         words,
+		colChars:         chars,
+		colBytes:         bytes,
+		colMaxLineLength: maxLineLength,
+	}
+
+	columns := []column{}
+	for _, col := range allColumns {
+		if selected[col] {
+			columns = append(columns, col)
+		}
+	}
+
+	// Print everything by default.
+	if len(columns) == 0 {
+		return allColumns
+	}
+
+	return columns
+}
+
+// Counts STDIN when no file names are given, otherwise counts each file and
+// prints a row per file followed by a total row.
+func run(fileNames []string, inReader io.Reader, outWriter io.Writer, cfg config) error {
+	if cfg.jobs < 1 {
+		return fmt.Errorf("%w: %d", ErrInvalidJobs, cfg.jobs)
+	}
+
+	if cfg.freq.top < 0 {
+		return fmt.Errorf("%w: %d", ErrInvalidFreq, cfg.freq.top)
+	}
+
+	if cfg.follow {
+		return runFollow(fileNames, outWriter, cfg)
+	}
+
+	if cfg.freq.top > 0 {
+		if cfg.root != "" {
+			return ErrRecursiveFreq
+		}
+
+		return runFreq(fileNames, inReader, outWriter, cfg)
+	}
+
+	if cfg.root != "" {
+		if len(fileNames) > 0 {
+			return ErrRecursiveArgs
+		}
+
+		return runRecursive(outWriter, cfg)
+	}
+
+	// Like coreutils, only print the total when more than one file is given.
+	p, err := newPrinter(cfg.format, outWriter, cfg.columns, len(fileNames) > 1)
+	if err != nil {
+		return err
+	}
+
+	// No file names provided; count STDIN.
+	if len(fileNames) == 0 {
+		if !cfg.raw {
+			if inReader, err = decompress(inReader); err != nil {
+				return err
+			}
+		}
+
+		c, err := count(inReader, cfg.split.new())
+		if err != nil {
+			return err
+		}
+
+		if err := p.row("", c); err != nil {
+			return err
+		}
+
+		if err := p.total(c); err != nil {
+			return err
+		}
+
+		return p.flush()
+	}
+
+	total := Counts{}
+	failed := false
+
+	for _, res := range countFiles(fileNames, cfg.jobs, func(fileName string) (Counts, error) {
+		return countFile(fileName, cfg)
+	}) {
+		if res.err != nil {
+			// Report the failure but keep counting the other files.
+			fmt.Fprintln(cfg.errWriter, res.err)
+			failed = true
+			continue
+		}
+
+		total.add(res.counts)
+		if err := p.row(res.fileName, res.counts); err != nil {
+			return err
+		}
+	}
+
+	if err := p.total(total); err != nil {
+		return err
+	}
+
+	if err := p.flush(); err != nil {
+		return err
+	}
+
+	if failed {
+		return ErrCountFailed
+	}
+
+	return nil
+}
 
-	// Determine the scanning behavior. See https://pkg.go.dev/bufio
-	if forLines {
-		scanner.Split(bufio.ScanLines)
-	} else {
-		scanner.Split(bufio.ScanWords)
+// Opens the provided file name and counts its content.
+func countFile(fileName string, cfg config) (Counts, error) {
+	f, err := openInput(fileName, cfg.raw)
+	if err != nil {
+		return Counts{}, err
 	}
+	defer f.Close()
 
-	// Count all the scanned words.
-	wc := 0
-	for scanner.Scan() {
-		wc++
+	c, err := count(f, cfg.split.new())
+	if err != nil {
+		return Counts{}, fmt.Errorf("%s: %w", fileName, err)
 	}
 
-	return wc
+	return c, nil
 }
@@ -2,23 +2,250 @@ package main
 
 import (
 	"bytes"
+	"errors"
+	"io"
+	"strings"
 	"testing"
+	"testing/iotest"
 )
 
-func TestCountWords(t *testing.T) {
-	b := bytes.NewBufferString("word1 word2 word3 word4\n")
-	exp := 4
-	res := count(b, false)
-	if res != exp {
-		t.Errorf("Expected %d, got %d instead.\n", exp, res)
+func TestCount(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected Counts
+	}{
+		{name: "Empty",
+			input:    "",
+			expected: Counts{}},
+		{name: "WordsOneLine",
+			input:    "word1 word2 word3 word4\n",
+			expected: Counts{Lines: 1, Words: 4, Bytes: 24, Chars: 24, MaxLineLength: 23}},
+		{name: "NoTrailingNewline",
+			input:    "word1 word2 word3\nline2\nline3 word1",
+			expected: Counts{Lines: 3, Words: 6, Bytes: 35, Chars: 35, MaxLineLength: 17}},
+		{name: "MultiByteChars",
+			input:    "こんにちは 世界\n",
+			expected: Counts{Lines: 1, Words: 2, Bytes: 23, Chars: 9, MaxLineLength: 8}},
+		{name: "CRLF",
+			input:    "a b\r\nc\r\n",
+			expected: Counts{Lines: 2, Words: 3, Bytes: 8, Chars: 8, MaxLineLength: 3}},
+		{name: "InvalidUTF8",
+			input:    "a\xffb \xe3\x81",
+			expected: Counts{Lines: 1, Words: 2, Bytes: 6, Chars: 6, MaxLineLength: 6}},
+		{name: "BlankLines",
+			input:    "\n\n  \n",
+			expected: Counts{Lines: 3, Words: 0, Bytes: 5, Chars: 5, MaxLineLength: 2}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := count(bytes.NewBufferString(tc.input), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if res != tc.expected {
+				t.Errorf("Expected %+v, got %+v instead.\n", tc.expected, res)
+			}
+		})
+	}
+}
+
+// Regression test for lines longer than the 64 KiB token limit of bufio.Scanner.
+func TestCountLongLine(t *testing.T) {
+	words := 1 << 20 // 4 MiB of "abc " in a single line
+	input := strings.Repeat("abc ", words) + "\n"
+
+	res, err := count(strings.NewReader(input), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Counts{
+		Lines:         1,
+		Words:         words,
+		Bytes:         len(input),
+		Chars:         len(input),
+		MaxLineLength: len(input) - 1,
+	}
+
+	if res != expected {
+		t.Errorf("Expected %+v, got %+v instead.\n", expected, res)
+	}
+}
+
+// Multi-byte characters split across reads must still be counted once.
+func TestCountOneByteReads(t *testing.T) {
+	input := "こんにちは 世界\nçà\n"
+
+	res, err := count(iotest.OneByteReader(strings.NewReader(input)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := count(strings.NewReader(input), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res != expected {
+		t.Errorf("Expected %+v, got %+v instead.\n", expected, res)
+	}
+}
+
+func TestCountReadError(t *testing.T) {
+	readErr := errors.New("read failed")
+	r := io.MultiReader(strings.NewReader("word1 word2\n"), iotest.ErrReader(readErr))
+
+	if _, err := count(r, nil); !errors.Is(err, readErr) {
+		t.Errorf("Expected error %q, got %v instead", readErr, err)
+	}
+}
+
+func TestRun(t *testing.T) {
+	testCases := []struct {
+		name        string
+		files       []string
+		columns     []column
+		jobs        int
+		expected    string
+		expectedErr error
+	}{
+		{name: "Stdin",
+			files:    []string{},
+			columns:  allColumns,
+			jobs:     1,
+			expected: "       2        3       17       17       11\n"},
+		{name: "StdinOneColumn",
+			files:    []string{},
+			columns:  []column{colWords},
+			jobs:     1,
+			expected: "3\n"},
+		{name: "OneFile",
+			files:    []string{"testdata/file1.txt"},
+			columns:  []column{colWords},
+			jobs:     1,
+			expected: "       4 testdata/file1.txt\n"},
+		{name: "MultipleFiles",
+			files:   []string{"testdata/file1.txt", "testdata/file2.txt"},
+			columns: []column{colLines, colWords},
+			jobs:    1,
+			expected: "       2        4 testdata/file1.txt\n" +
+				"       3        6 testdata/file2.txt\n" +
+				"       5       10 total\n"},
+		{name: "MissingFile",
+			files:   []string{"testdata/file1.txt", "testdata/nofile.txt", "testdata/file2.txt"},
+			columns: []column{colWords},
+			jobs:    1,
+			expected: "       4 testdata/file1.txt\n" +
+				"       6 testdata/file2.txt\n" +
+				"      10 total\n",
+			expectedErr: ErrCountFailed},
+		{name: "MissingFileParallel",
+			files:   []string{"testdata/file1.txt", "testdata/nofile.txt", "testdata/file2.txt"},
+			columns: []column{colWords},
+			jobs:    3,
+			expected: "       4 testdata/file1.txt\n" +
+				"       6 testdata/file2.txt\n" +
+				"      10 total\n",
+			expectedErr: ErrCountFailed},
+		{name: "InvalidJobs",
+			files:       []string{"testdata/file1.txt"},
+			columns:     []column{colWords},
+			jobs:        0,
+			expected:    "",
+			expectedErr: ErrInvalidJobs},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var outWriter, errWriter bytes.Buffer
+			in := bytes.NewBufferString("word1 word2\nword3")
+			cfg := config{columns: tc.columns, jobs: tc.jobs, format: "text", errWriter: &errWriter}
+
+			err := run(tc.files, in, &outWriter, cfg)
+
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("Expected error %v, got %v instead", tc.expectedErr, err)
+			}
+
+			if errors.Is(tc.expectedErr, ErrCountFailed) && errWriter.Len() == 0 {
+				t.Errorf("Expected an error message for the failed file")
+			}
+
+			if outWriter.String() != tc.expected {
+				t.Errorf("Expected %q, got %q instead", tc.expected, outWriter.String())
+			}
+		})
 	}
 }
 
-func TestCountLines(t *testing.T) {
-	b := bytes.NewBufferString("word1 word2 word3\nline2\nline3 word1")
-	exp := 3
-	res := count(b, true)
-	if res != exp {
-		t.Errorf("Expected %d, got %d instead.\n", exp, res)
+func TestSelectColumns(t *testing.T) {
+	res := selectColumns(false, false, false, false, false)
+	if len(res) != len(allColumns) {
+		t.Errorf("Expected all %d columns by default, got %v instead", len(allColumns), res)
+	}
+
+	res = selectColumns(true, false, false, true, false)
+	if len(res) != 2 || res[0] != colLines || res[1] != colBytes {
+		t.Errorf("Expected lines and bytes columns, got %v instead", res)
+	}
+}
+
+func TestRunFormat(t *testing.T) {
+	testCases := []struct {
+		name        string
+		files       []string
+		format      string
+		expected    string
+		expectedErr error
+	}{
+		{name: "JSON",
+			files:  []string{"testdata/file1.txt", "testdata/file2.txt"},
+			format: "json",
+			expected: `{"files":[` +
+				`{"lines":2,"name":"testdata/file1.txt","words":4},` +
+				`{"lines":3,"name":"testdata/file2.txt","words":6}],` +
+				`"total":{"lines":5,"words":10}}` + "\n"},
+		{name: "JSONStdin",
+			files:    []string{},
+			format:   "json",
+			expected: `{"files":[{"lines":2,"name":"-","words":3}],"total":{"lines":2,"words":3}}` + "\n"},
+		{name: "CSV",
+			files:  []string{"testdata/file1.txt", "testdata/file2.txt"},
+			format: "csv",
+			expected: "name,lines,words\n" +
+				"testdata/file1.txt,2,4\n" +
+				"testdata/file2.txt,3,6\n" +
+				"total,5,10\n"},
+		{name: "InvalidFormat",
+			files:       []string{"testdata/file1.txt"},
+			format:      "xml",
+			expected:    "",
+			expectedErr: ErrInvalidFormat},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var outWriter, errWriter bytes.Buffer
+			in := bytes.NewBufferString("word1 word2\nword3")
+			cfg := config{
+				columns:   []column{colLines, colWords},
+				jobs:      1,
+				format:    tc.format,
+				errWriter: &errWriter,
+			}
+
+			err := run(tc.files, in, &outWriter, cfg)
+
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("Expected error %v, got %v instead", tc.expectedErr, err)
+			}
+
+			if outWriter.String() != tc.expected {
+				t.Errorf("Expected %q, got %q instead", tc.expected, outWriter.String())
+			}
+		})
 	}
 }
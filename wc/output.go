@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// The name used for STDIN in machine-readable formats.
+const stdinName = "-"
+
+// Prints the counts in a specific output format.
+type printer interface {
+	row(name string, c Counts) error // a single input; an empty name means STDIN
+	total(c Counts) error            // the totals of all inputs
+	flush() error                    // writes anything that is still buffered
+}
+
+// Creates the printer for the given format name. The text format omits the
+// total row unless showTotal is set; the other formats always include it.
+func newPrinter(format string, outWriter io.Writer, columns []column, showTotal bool) (printer, error) {
+	switch format {
+	case "text":
+		return &textPrinter{out: outWriter, columns: columns, showTotal: showTotal}, nil
+	case "json":
+		return &jsonPrinter{out: outWriter, columns: columns, files: []map[string]interface{}{}}, nil
+	case "csv":
+		return &csvPrinter{w: csv.NewWriter(outWriter), columns: columns}, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrInvalidFormat, format)
+}
+
+// Prints a human-readable table similar to coreutils.
+type textPrinter struct {
+	out       io.Writer
+	columns   []column
+	showTotal bool
+}
+
+// Prints a single row of the result table. A single counter without a name is
+// printed as a bare number so that the output is easy to use in scripts.
+func (p *textPrinter) row(name string, c Counts) error {
+	if name == "" && len(p.columns) == 1 {
+		_, err := fmt.Fprintln(p.out, p.columns[0].value(c))
+		return err
+	}
+
+	fields := make([]string, 0, len(p.columns)+1)
+	for _, col := range p.columns {
+		fields = append(fields, fmt.Sprintf("%8d", col.value(c)))
+	}
+
+	if name != "" {
+		fields = append(fields, name)
+	}
+
+	_, err := fmt.Fprintln(p.out, strings.Join(fields, " "))
+	return err
+}
+
+func (p *textPrinter) total(c Counts) error {
+	if !p.showTotal {
+		return nil
+	}
+
+	return p.row("total", c)
+}
+
+func (p *textPrinter) flush() error {
+	return nil
+}
+
+// Collects the rows and prints a single JSON document when flushed.
+//
+//	{"files":[{"lines":93,"name":"main.go"}],"total":{"lines":93}}
+type jsonPrinter struct {
+	out     io.Writer
+	columns []column
+	files   []map[string]interface{}
+	totals  map[string]interface{}
+}
+
+func (p *jsonPrinter) row(name string, c Counts) error {
+	if name == "" {
+		name = stdinName
+	}
+
+	obj := p.object(c)
+	obj["name"] = name
+	p.files = append(p.files, obj)
+
+	return nil
+}
+
+func (p *jsonPrinter) total(c Counts) error {
+	p.totals = p.object(c)
+	return nil
+}
+
+func (p *jsonPrinter) flush() error {
+	doc := struct {
+		Files []map[string]interface{} `json:"files"`
+		Total map[string]interface{}   `json:"total"`
+	}{Files: p.files, Total: p.totals}
+
+	return json.NewEncoder(p.out).Encode(doc)
+}
+
+// Maps each selected column name to its value.
+func (p *jsonPrinter) object(c Counts) map[string]interface{} {
+	obj := map[string]interface{}{}
+	for _, col := range p.columns {
+		obj[col.String()] = col.value(c)
+	}
+
+	return obj
+}
+
+// Prints a CSV table with a header row and a final total row.
+type csvPrinter struct {
+	w             *csv.Writer
+	columns       []column
+	headerPrinted bool
+}
+
+func (p *csvPrinter) row(name string, c Counts) error {
+	if name == "" {
+		name = stdinName
+	}
+
+	if !p.headerPrinted {
+		header := []string{"name"}
+		for _, col := range p.columns {
+			header = append(header, col.String())
+		}
+
+		if err := p.w.Write(header); err != nil {
+			return err
+		}
+		p.headerPrinted = true
+	}
+
+	record := []string{name}
+	for _, col := range p.columns {
+		record = append(record, strconv.Itoa(col.value(c)))
+	}
+
+	return p.w.Write(record)
+}
+
+func (p *csvPrinter) total(c Counts) error {
+	return p.row("total", c)
+}
+
+func (p *csvPrinter) flush() error {
+	p.w.Flush()
+	return p.w.Error()
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+)
+
+// The most data kept while looking for the next match of -split-match. Input
+// without a match is dropped beyond it instead of filling the buffer, and
+// lines longer than it lose their start for ^ and \b.
+const maxPendingMatch = 64 * 1024
+
+// Returns a new split function for each input, since split functions may keep
+// state between the calls made by a scanner.
+type splitter func() bufio.SplitFunc
+
+// Returns the split function of the splitter, or nil when there is none.
+func (s splitter) new() bufio.SplitFunc {
+	if s == nil {
+		return nil
+	}
+
+	return s()
+}
+
+// Builds a splitter of bufio.SplitFuncs from a regular expression.
+//
+// By default the expression is a delimiter and the tokens are whatever sits
+// between its matches. When matchTokens is true, the matches themselves are the
+// tokens. Empty tokens, such as the one between two adjacent delimiters, are
+// skipped the same way bufio.ScanWords skips repeated spaces.
+//
+// The expression is in multi-line mode: ^ and $ match at the start and end of
+// every line, wherever the boundaries of the buffer fall.
+func newRegexpSplit(expr string, matchTokens bool) (splitter, error) {
+	re, err := regexp.Compile("(?m)" + expr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidSplit, err)
+	}
+
+	// An expression that matches the empty string would never make progress.
+	if re.MatchString("") {
+		return nil, fmt.Errorf("%w: %q matches the empty string", ErrInvalidSplit, expr)
+	}
+
+	// The matches of ^ and \b depend on the byte before them, which the
+	// regexp package cannot see at the start of the data. For such
+	// expressions, the data is kept from the start of the current line and
+	// skip is the part of it that was already consumed.
+	lineContext := needsLineContext(expr)
+
+	return func() bufio.SplitFunc {
+		return regexpSplit(re, matchTokens, lineContext)
+	}, nil
+}
+
+func regexpSplit(re *regexp.Regexp, matchTokens, lineContext bool) bufio.SplitFunc {
+	skip := 0
+
+	// Returns the first match starting at or after offset.
+	find := func(data []byte, offset int) []int {
+		if !lineContext {
+			loc := re.FindIndex(data[offset:])
+			if loc == nil {
+				return nil
+			}
+			return []int{offset + loc[0], offset + loc[1]}
+		}
+
+		// Only the successive matches of a single search see the bytes
+		// before them, so the matches are searched from the start of the
+		// line, asking for more of them until one starts at the offset.
+		lineStart := bytes.LastIndexByte(data[:offset], '\n') + 1
+		for n := 4; ; n *= 2 {
+			locs := re.FindAllIndex(data[lineStart:], n)
+			for _, loc := range locs {
+				if lineStart+loc[0] >= offset {
+					return []int{lineStart + loc[0], lineStart + loc[1]}
+				}
+			}
+
+			if len(locs) < n {
+				return nil
+			}
+		}
+	}
+
+	// Returns how far to advance to consume the data up to end.
+	consume := func(data []byte, end int) int {
+		skip = 0
+		if !lineContext {
+			return end
+		}
+
+		lineStart := bytes.LastIndexByte(data[:end], '\n') + 1
+		if end-lineStart > maxPendingMatch {
+			return end
+		}
+
+		skip = end - lineStart
+		return lineStart
+	}
+
+	return func(data []byte, atEOF bool) (advance int, token []byte, err error) {
+		if skip > len(data) {
+			skip = 0
+		}
+
+		if atEOF && len(data) == skip {
+			skip = 0
+			return len(data), nil, nil
+		}
+
+		// Delimiters at the start of the data would produce empty tokens, so
+		// skip over them.
+		offset := skip
+
+		for {
+			loc := find(data, offset)
+
+			// A match that reaches the end of the buffer may continue in the data
+			// that has not been read yet, so request more data before using it.
+			if loc == nil || (loc[1] == len(data) && !atEOF) {
+				if !atEOF {
+					keep := offset
+					if matchTokens && loc != nil {
+						keep = loc[0]
+					} else if matchTokens && len(data)-keep > maxPendingMatch {
+						keep = len(data) - maxPendingMatch
+					}
+
+					return consume(data, keep), nil, nil
+				}
+
+				// No more matches. The rest of the input is the last token when
+				// splitting on delimiters.
+				skip = 0
+				rest := data[offset:]
+				if matchTokens || len(rest) == 0 {
+					return len(data), nil, nil
+				}
+
+				return len(data), rest, nil
+			}
+
+			start, end := loc[0], loc[1]
+
+			if matchTokens {
+				return consume(data, end), data[start:end], nil
+			}
+
+			if start == offset {
+				offset = end
+				continue
+			}
+
+			return consume(data, end), data[offset:start], nil
+		}
+	}
+}
+
+// Reports whether the expression has assertions that look at the byte before
+// them: ^, \A, \b or \B.
+func needsLineContext(expr string) bool {
+	re, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return false
+	}
+
+	var walk func(re *syntax.Regexp) bool
+	walk = func(re *syntax.Regexp) bool {
+		switch re.Op {
+		case syntax.OpBeginLine, syntax.OpBeginText, syntax.OpWordBoundary, syntax.OpNoWordBoundary:
+			return true
+		}
+
+		for _, sub := range re.Sub {
+			if walk(sub) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return walk(re)
+}
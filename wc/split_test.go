@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestRegexpSplit(t *testing.T) {
+	testCases := []struct {
+		name        string
+		expr        string
+		matchTokens bool
+		input       string
+		expected    []string
+	}{
+		{name: "Delimiter",
+			expr:     ",",
+			input:    "a,b,c",
+			expected: []string{"a", "b", "c"}},
+		{name: "EmptyTokens",
+			expr:     ",",
+			input:    ",a,,b,,,c,",
+			expected: []string{"a", "b", "c"}},
+		{name: "OnlyDelimiters",
+			expr:     ",",
+			input:    ",,,",
+			expected: nil},
+		{name: "Empty",
+			expr:     ",",
+			input:    "",
+			expected: nil},
+		{name: "Sentences",
+			expr:     `[.!?]\s*`,
+			input:    "One. Two! Three?",
+			expected: []string{"One", "Two", "Three"}},
+		{name: "GreedyDelimiterAcrossBoundary",
+			expr:     `-+`,
+			input:    "a---b----c",
+			expected: []string{"a", "b", "c"}},
+		{name: "MultiByteDelimiterAcrossBoundary",
+			expr:     `<sep>`,
+			input:    "first<sep>second<sep>third",
+			expected: []string{"first", "second", "third"}},
+		{name: "Matches",
+			expr:        `[A-Za-z_][A-Za-z0-9_]*`,
+			matchTokens: true,
+			input:       "x := foo(bar_1, 42)",
+			expected:    []string{"x", "foo", "bar_1"}},
+		{name: "MatchAcrossBoundary",
+			expr:        `[0-9]+`,
+			matchTokens: true,
+			input:       "a 12345 b 678",
+			expected:    []string{"12345", "678"}},
+		{name: "LineStart",
+			expr:        `^a`,
+			matchTokens: true,
+			input:       "aaa\nab\nba\n",
+			expected:    []string{"a", "a"}},
+		{name: "WordBoundary",
+			expr:        `\ba`,
+			matchTokens: true,
+			input:       "aaa ba ab",
+			expected:    []string{"a", "a"}},
+		{name: "LineStartDelimiter",
+			expr:     `^-`,
+			input:    "a-b\n-c",
+			expected: []string{"a-b\n", "c"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			split, err := newRegexpSplit(tc.expr, tc.matchTokens)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Reading one byte at a time makes every match cross a buffer
+			// boundary of the scanner.
+			readers := map[string]io.Reader{
+				"Whole":   strings.NewReader(tc.input),
+				"OneByte": iotest.OneByteReader(strings.NewReader(tc.input)),
+			}
+
+			for readerName, r := range readers {
+				scanner := bufio.NewScanner(r)
+				scanner.Split(split())
+
+				var res []string
+				for scanner.Scan() {
+					res = append(res, scanner.Text())
+				}
+
+				if err := scanner.Err(); err != nil {
+					t.Fatal(err)
+				}
+
+				if !reflect.DeepEqual(res, tc.expected) {
+					t.Errorf("%s: expected %q, got %q instead", readerName, tc.expected, res)
+				}
+			}
+		})
+	}
+}
+
+func TestRegexpSplitBoundedBuffer(t *testing.T) {
+	split, err := newRegexpSplit(`[0-9]+`, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Input without a match is not kept while looking for the next one.
+	input := strings.Repeat("x", 16*maxPendingMatch) + " 42"
+	scanner := bufio.NewScanner(strings.NewReader(input))
+	scanner.Buffer(make([]byte, 0, 4096), 4*maxPendingMatch)
+	scanner.Split(split())
+
+	var res []string
+	for scanner.Scan() {
+		res = append(res, scanner.Text())
+	}
+
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(res, []string{"42"}) {
+		t.Errorf("Expected %q, got %q instead", []string{"42"}, res)
+	}
+}
+
+func TestRegexpSplitInvalid(t *testing.T) {
+	for _, expr := range []string{"[", `\s*`} {
+		if _, err := newRegexpSplit(expr, false); !errors.Is(err, ErrInvalidSplit) {
+			t.Errorf("Expected error %q for %q, got %v instead", ErrInvalidSplit, expr, err)
+		}
+	}
+}
+
+func TestCountWithSplit(t *testing.T) {
+	split, err := newRegexpSplit(",", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := count(strings.NewReader("a,b,,c\nd,e\n"), split())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The newline is part of the tokens because only commas delimit them.
+	expected := Counts{Lines: 2, Words: 4, Bytes: 11, Chars: 11, MaxLineLength: 6}
+	if res != expected {
+		t.Errorf("Expected %+v, got %+v instead", expected, res)
+	}
+}
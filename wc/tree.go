@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Files whose first bytes contain a NUL byte are considered binary, which is
+// the same heuristic git uses.
+const binarySniffLen = 8000
+
+// The group name for files without an extension.
+const noExtension = "(none)"
+
+// A list of glob patterns implementing flag.Value so that a flag can be
+// repeated.
+type globList []string
+
+func (g *globList) String() string {
+	return strings.Join(*g, ",")
+}
+
+func (g *globList) Set(pattern string) error {
+	*g = append(*g, pattern)
+	return nil
+}
+
+// A compiled glob pattern.
+//
+// Patterns without a slash match the base name of a file at any depth, such as
+// "*.go". Patterns with a slash match the path relative to the root, where
+// "**" matches any number of directories, such as "vendor/**".
+type glob struct {
+	re       *regexp.Regexp
+	fullPath bool
+}
+
+func compileGlob(pattern string) (glob, error) {
+	var expr strings.Builder
+	expr.WriteString("^")
+
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			if strings.HasPrefix(pattern[i:], "**/") {
+				expr.WriteString("(?:.*/)?")
+				i += 2
+			} else if strings.HasPrefix(pattern[i:], "**") {
+				expr.WriteString(".*")
+				i++
+			} else {
+				expr.WriteString("[^/]*")
+			}
+		case '?':
+			expr.WriteString("[^/]")
+		case '[':
+			// Copy a character class as is, translating the negation.
+			end := strings.IndexByte(pattern[i:], ']')
+			if end < 0 {
+				return glob{}, fmt.Errorf("%w: %s", ErrInvalidGlob, pattern)
+			}
+			class := pattern[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expr.WriteString("[" + class + "]")
+			i += end
+		default:
+			// Quote the whole character, which may span several bytes.
+			_, size := utf8.DecodeRuneInString(pattern[i:])
+			expr.WriteString(regexp.QuoteMeta(pattern[i : i+size]))
+			i += size - 1
+		}
+	}
+
+	expr.WriteString("$")
+
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return glob{}, fmt.Errorf("%w: %s", ErrInvalidGlob, pattern)
+	}
+
+	return glob{re: re, fullPath: strings.Contains(pattern, "/")}, nil
+}
+
+// Reports whether the slash-separated path relative to the root matches.
+func (g glob) match(relPath string) bool {
+	if g.fullPath {
+		return g.re.MatchString(relPath)
+	}
+
+	return g.re.MatchString(pathBase(relPath))
+}
+
+// Like path.Base but keeps the result empty for a trailing slash, so that a
+// base name pattern never matches a directory marker.
+func pathBase(relPath string) string {
+	return relPath[strings.LastIndexByte(relPath, '/')+1:]
+}
+
+// Compiles all the patterns of the list.
+func compileGlobs(patterns globList) ([]glob, error) {
+	globs := make([]glob, 0, len(patterns))
+	for _, pattern := range patterns {
+		g, err := compileGlob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		globs = append(globs, g)
+	}
+
+	return globs, nil
+}
+
+// Reports whether any of the globs matches.
+func matchAny(globs []glob, relPath string) bool {
+	for _, g := range globs {
+		if g.match(relPath) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Collects the files under the root directory that should be counted.
+func findFiles(root string, includes, excludes []glob, errWriter io.Writer) ([]string, bool, error) {
+	fileNames := []string{}
+	failed := false
+
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// The root itself must exist; anything else is reported and skipped.
+			if path == root {
+				return err
+			}
+
+			fmt.Fprintln(errWriter, err)
+			failed = true
+			return nil
+		}
+
+		relPath, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		relPath = filepath.ToSlash(relPath)
+
+		if info.IsDir() {
+			// A trailing slash lets "dir/**" exclude the directory itself.
+			if relPath != "." && (matchAny(excludes, relPath) || matchAny(excludes, relPath+"/")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !info.Mode().IsRegular() || matchAny(excludes, relPath) {
+			return nil
+		}
+
+		if len(includes) > 0 && !matchAny(includes, relPath) {
+			return nil
+		}
+
+		fileNames = append(fileNames, path)
+		return nil
+	})
+
+	return fileNames, failed, err
+}
+
+// Opens the provided file name and counts its content unless it is binary, in
+// which case ErrBinaryFile is returned. Compressed files are checked after
+// decompression.
+func countTextFile(fileName string, cfg config) (Counts, error) {
+	f, err := openInput(fileName, cfg.raw)
+	if err != nil {
+		return Counts{}, err
+	}
+	defer f.Close()
+
+	reader := bufio.NewReaderSize(f, binarySniffLen)
+
+	// Peek returns io.EOF for files smaller than the sniffing length.
+	head, err := reader.Peek(binarySniffLen)
+	if err != nil && err != io.EOF {
+		return Counts{}, fmt.Errorf("%s: %w", fileName, err)
+	}
+
+	if bytes.IndexByte(head, 0) >= 0 {
+		return Counts{}, fmt.Errorf("%s: %w", fileName, ErrBinaryFile)
+	}
+
+	c, err := count(reader, cfg.split.new())
+	if err != nil {
+		return Counts{}, fmt.Errorf("%s: %w", fileName, err)
+	}
+
+	return c, nil
+}
+
+// Returns the name of the group a file belongs to in recursive mode.
+func extensionGroup(fileName string) string {
+	if ext := filepath.Ext(fileName); ext != "" {
+		return ext
+	}
+
+	return noExtension
+}
+
+// Walks the root directory and prints subtotals per file extension followed by
+// the grand total.
+func runRecursive(outWriter io.Writer, cfg config) error {
+	p, err := newPrinter(cfg.format, outWriter, cfg.columns, true)
+	if err != nil {
+		return err
+	}
+
+	includes, err := compileGlobs(cfg.include)
+	if err != nil {
+		return err
+	}
+
+	excludes, err := compileGlobs(cfg.exclude)
+	if err != nil {
+		return err
+	}
+
+	fileNames, failed, err := findFiles(cfg.root, includes, excludes, cfg.errWriter)
+	if err != nil {
+		return err
+	}
+
+	groups := map[string]Counts{}
+	total := Counts{}
+
+	results := countFiles(fileNames, cfg.jobs, func(fileName string) (Counts, error) {
+		return countTextFile(fileName, cfg)
+	})
+
+	for _, res := range results {
+		if errors.Is(res.err, ErrBinaryFile) {
+			continue
+		}
+
+		if res.err != nil {
+			// Report the failure but keep counting the other files.
+			fmt.Fprintln(cfg.errWriter, res.err)
+			failed = true
+			continue
+		}
+
+		group := extensionGroup(res.fileName)
+		subtotal := groups[group]
+		subtotal.add(res.counts)
+		groups[group] = subtotal
+		total.add(res.counts)
+	}
+
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := p.row(name, groups[name]); err != nil {
+			return err
+		}
+	}
+
+	if err := p.total(total); err != nil {
+		return err
+	}
+
+	if err := p.flush(); err != nil {
+		return err
+	}
+
+	if failed {
+		return ErrCountFailed
+	}
+
+	return nil
+}
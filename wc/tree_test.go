@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestGlobMatch(t *testing.T) {
+	testCases := []struct {
+		pattern  string
+		path     string
+		expected bool
+	}{
+		{pattern: "*.go", path: "main.go", expected: true},
+		{pattern: "*.go", path: "sub/dir/main.go", expected: true},
+		{pattern: "*.go", path: "main.go.txt", expected: false},
+		{pattern: "vendor/**", path: "vendor/", expected: true},
+		{pattern: "vendor/**", path: "vendor/lib/lib.go", expected: true},
+		{pattern: "vendor/**", path: "sub/vendor/lib.go", expected: false},
+		{pattern: "**/testdata/*", path: "testdata/a.txt", expected: true},
+		{pattern: "**/testdata/*", path: "a/b/testdata/a.txt", expected: true},
+		{pattern: "sub/*.txt", path: "sub/notes.txt", expected: true},
+		{pattern: "sub/*.txt", path: "sub/deep/notes.txt", expected: false},
+		{pattern: "file?.[ct]xt", path: "file1.txt", expected: true},
+		{pattern: "file?.[!t]xt", path: "file1.txt", expected: false},
+		{pattern: "*.日本", path: "報告.日本", expected: true},
+		{pattern: "?.txt", path: "日.txt", expected: true},
+	}
+
+	for _, tc := range testCases {
+		g, err := compileGlob(tc.pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if res := g.match(tc.path); res != tc.expected {
+			t.Errorf("Expected %q matching %q to be %t, got %t instead", tc.pattern, tc.path, tc.expected, res)
+		}
+	}
+}
+
+func TestCompileGlobInvalid(t *testing.T) {
+	if _, err := compileGlob("[abc"); !errors.Is(err, ErrInvalidGlob) {
+		t.Errorf("Expected error %q, got %v instead", ErrInvalidGlob, err)
+	}
+}
+
+func TestRunRecursive(t *testing.T) {
+	testCases := []struct {
+		name     string
+		include  globList
+		exclude  globList
+		expected string
+	}{
+		{name: "All",
+			expected: "       1 (none)\n" +
+				"       7 .go\n" +
+				"       2 .txt\n" +
+				"      10 total\n"},
+		{name: "IncludeExclude",
+			include: globList{"*.go"},
+			exclude: globList{"vendor/**"},
+			expected: "       4 .go\n" +
+				"       4 total\n"},
+		{name: "ExcludeBaseName",
+			exclude: globList{"*.txt", "Makefile"},
+			expected: "       7 .go\n" +
+				"       7 total\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var outWriter, errWriter bytes.Buffer
+			cfg := config{
+				columns:   []column{colLines},
+				jobs:      2,
+				format:    "text",
+				root:      "testdata/tree",
+				include:   tc.include,
+				exclude:   tc.exclude,
+				errWriter: &errWriter,
+			}
+
+			if err := run(nil, nil, &outWriter, cfg); err != nil {
+				t.Fatal(err)
+			}
+
+			if outWriter.String() != tc.expected {
+				t.Errorf("Expected %q, got %q instead", tc.expected, outWriter.String())
+			}
+		})
+	}
+}
+
+func TestRunRecursiveErrors(t *testing.T) {
+	var outWriter, errWriter bytes.Buffer
+	cfg := config{columns: []column{colLines}, jobs: 1, format: "text", errWriter: &errWriter}
+
+	cfg.root = "testdata/tree"
+	if err := run([]string{"main.go"}, nil, &outWriter, cfg); !errors.Is(err, ErrRecursiveArgs) {
+		t.Errorf("Expected error %q, got %v instead", ErrRecursiveArgs, err)
+	}
+
+	cfg.freq.top = 2
+	if err := run(nil, nil, &outWriter, cfg); !errors.Is(err, ErrRecursiveFreq) {
+		t.Errorf("Expected error %q, got %v instead", ErrRecursiveFreq, err)
+	}
+	cfg.freq.top = 0
+
+	cfg.root = "testdata/nodir"
+	if err := run(nil, nil, &outWriter, cfg); err == nil {
+		t.Errorf("Expected an error for a missing root directory")
+	}
+}
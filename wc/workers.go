@@ -0,0 +1,53 @@
+package main
+
+import "sync"
+
+// The outcome of counting a single file.
+type fileResult struct {
+	fileName string
+	counts   Counts
+	err      error
+}
+
+// Counts the files with countFunc on the given number of worker goroutines. The
+// results are returned in the same order as the file names regardless of which
+// worker finishes first.
+func countFiles(fileNames []string, jobs int, countFunc func(fileName string) (Counts, error)) []fileResult {
+	results := make([]fileResult, len(fileNames))
+
+	// A queue of indices into fileNames waiting to be processed.
+	chIndex := make(chan int)
+
+	// The WaitGroup provides a mechanism to coordinate the goroutine execution.
+	wg := sync.WaitGroup{}
+
+	go func() {
+		// At the end, close the channel indicating no more work is left to do.
+		defer close(chIndex)
+
+		for i := range fileNames {
+			chIndex <- i
+		}
+	}()
+
+	for i := 0; i < jobs; i++ {
+		wg.Add(1)
+
+		// A worker goroutine.
+		go func() {
+			defer wg.Done()
+
+			// Each worker writes to its own slots of the results slice, so no other
+			// synchronization is needed.
+			for i := range chIndex {
+				c, err := countFunc(fileNames[i])
+				results[i] = fileResult{fileName: fileNames[i], counts: c, err: err}
+			}
+		}()
+	}
+
+	// Wait until all files have been processed.
+	wg.Wait()
+
+	return results
+}
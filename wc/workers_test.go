@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCountFilesParallel(t *testing.T) {
+	tempDir := t.TempDir()
+
+	// Create files of different sizes so that workers finish out of order.
+	fileNames := []string{}
+	for i := 0; i < 50; i++ {
+		fileName := filepath.Join(tempDir, fmt.Sprintf("file%02d.txt", i))
+		content := strings.Repeat(fmt.Sprintf("line %d of words\n", i), (i*37)%101+1)
+		if err := os.WriteFile(fileName, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		fileNames = append(fileNames, fileName)
+	}
+
+	countFunc := func(fileName string) (Counts, error) {
+		return countFile(fileName, config{})
+	}
+
+	sequential := countFiles(fileNames, 1, countFunc)
+	parallel := countFiles(fileNames, 8, countFunc)
+
+	if len(sequential) != len(parallel) {
+		t.Fatalf("Expected %d results, got %d instead", len(sequential), len(parallel))
+	}
+
+	seqTotal, parTotal := Counts{}, Counts{}
+	for i := range sequential {
+		if parallel[i].fileName != fileNames[i] {
+			t.Errorf("Expected result %d for %q, got %q instead", i, fileNames[i], parallel[i].fileName)
+		}
+
+		if parallel[i].counts != sequential[i].counts {
+			t.Errorf("Expected %+v for %q, got %+v instead", sequential[i].counts, fileNames[i], parallel[i].counts)
+		}
+
+		seqTotal.add(sequential[i].counts)
+		parTotal.add(parallel[i].counts)
+	}
+
+	if seqTotal != parTotal {
+		t.Errorf("Expected total %+v, got %+v instead", seqTotal, parTotal)
+	}
+}